@@ -0,0 +1,164 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Add and Flush after Close has been called.
+var ErrClosed = errors.New("batcher closed")
+
+/*
+Batcher accumulates items and hands them to a flush callback in batches, once size items are
+pending or the oldest pending item has waited for latency, whichever comes first. It turns many
+small writes into a few big ones, e.g. before they hit a conn, while bounding the delay any single
+item can see.
+
+Every flush runs on the batcher's own goroutine, one at a time and in order, so the callback
+doesn't need to be safe for concurrent use. While it runs, Add blocks, which pushes back on producers.
+*/
+type Batcher[T any] struct {
+	flush   func(batch []T)
+	size    int
+	latency time.Duration
+
+	items   chan T
+	flushes chan chan struct{}
+	done    chan struct{}
+
+	//protects closed, and makes sure nobody sends on items once it's closed.
+	mu     sync.RWMutex
+	closed bool
+	//closed by Close before it takes mu, wakes Add and Flush blocked on a running flush.
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a Batcher flushing batches of at most size items, at least every latency.
+func New[T any](flush func(batch []T), size int, latency time.Duration) *Batcher[T] {
+	b := &Batcher[T]{
+		flush:   flush,
+		size:    size,
+		latency: latency,
+		items:   make(chan T),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+
+	go b.run()
+	return b
+}
+
+// Add queues item for the next batch, blocking while a flush is running or until ctx is done.
+func (b *Batcher[T]) Add(ctx context.Context, item T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+
+	select {
+	case b.items <- item:
+		return nil
+	case <-b.closing:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush flushes the pending items right away and returns once the callback returned.
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+
+	ack := make(chan struct{})
+	select {
+	case b.flushes <- ack:
+	case <-b.closing:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-b.closing:
+		//the flush still happens, Close waits for it.
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes what is pending and stops the batcher, or returns ctx.Err() if the last flush
+// doesn't finish in time.
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	//a blocked Add or Flush holds mu for reading, let it go before asking for the write lock.
+	b.closeOnce.Do(func() { close(b.closing) })
+
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.items)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Batcher[T]) run() {
+	defer close(b.done)
+
+	pending := make([]T, 0, b.size)
+	//only armed while something is pending.
+	timer := time.NewTimer(b.latency)
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(pending) == 0 {
+			return
+		}
+		b.flush(pending)
+		//the callback might keep the slice, so start over with a new one.
+		pending = make([]T, 0, b.size)
+	}
+
+	for {
+		select {
+		case item, ok := <-b.items:
+			if !ok {
+				flush()
+				return
+			}
+
+			pending = append(pending, item)
+			if len(pending) == 1 {
+				timer.Reset(b.latency)
+			}
+			if len(pending) >= b.size {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		case ack := <-b.flushes:
+			flush()
+			close(ack)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (r *recorder) flush(batch []int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch)
+}
+
+func (r *recorder) get() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.batches)
+}
+
+func TestBatcherSize(t *testing.T) {
+	r := &recorder{}
+	b := New(r.flush, 3, time.Hour)
+	ctx := context.Background()
+
+	for i := range 7 {
+		if err := b.Add(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]int{{0, 1, 2}, {3, 4, 5}, {6}}
+	if !slices.EqualFunc(r.get(), expected, slices.Equal) {
+		t.Errorf("expected %v; actual %v", expected, r.get())
+	}
+
+	if err := b.Add(ctx, 7); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v; actual %v", ErrClosed, err)
+	}
+}
+
+func TestBatcherLatency(t *testing.T) {
+	r := &recorder{}
+	b := New(r.flush, 100, time.Millisecond*20)
+	defer b.Close(context.Background())
+
+	_ = b.Add(context.Background(), 1)
+	_ = b.Add(context.Background(), 2)
+
+	deadline := time.Now().Add(time.Second)
+	for len(r.get()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the batch to be flushed after the latency")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	if got := r.get(); !slices.Equal(got[0], []int{1, 2}) {
+		t.Errorf("expected [1 2]; actual %v", got[0])
+	}
+}
+
+func TestBatcherFlush(t *testing.T) {
+	r := &recorder{}
+	b := New(r.flush, 100, time.Hour)
+	defer b.Close(context.Background())
+
+	_ = b.Add(context.Background(), 1)
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.get(); len(got) != 1 || !slices.Equal(got[0], []int{1}) {
+		t.Errorf("expected [[1]]; actual %v", got)
+	}
+}
+
+func TestBatcherCloseStalledFlush(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	b := New(func(batch []int) {
+		close(started)
+		<-release
+	}, 1, time.Hour)
+
+	//the first item fills a batch, the flush stalls.
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	//blocked behind the flush without a deadline, holding the read lock.
+	added := make(chan error, 1)
+	go func() { added <- b.Add(context.Background(), 2) }()
+	time.Sleep(time.Millisecond * 20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() { closed <- b.Close(ctx) }()
+
+	select {
+	case err := <-closed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v; actual %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Close to return once ctx expired")
+	}
+
+	select {
+	case err := <-added:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("expected %v; actual %v", ErrClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the blocked Add to be woken up")
+	}
+}
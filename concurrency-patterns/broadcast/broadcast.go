@@ -0,0 +1,122 @@
+package broadcast
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Subscriber receives every value published while it's subscribed.
+type Subscriber[T any] struct {
+	b       *Broadcaster[T]
+	ch      chan T
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// C returns the channel the values are delivered on, closed once the subscriber leaves.
+func (s *Subscriber[T]) C() <-chan T {
+	return s.ch
+}
+
+// Lag returns the number of values waiting in the subscriber's buffer.
+func (s *Subscriber[T]) Lag() int {
+	return len(s.ch)
+}
+
+// Dropped returns the number of values the subscriber lost because its buffer was full.
+func (s *Subscriber[T]) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Leave unsubscribes and closes C, it's safe to call more than once.
+func (s *Subscriber[T]) Leave() {
+	s.once.Do(func() {
+		s.b.mu.Lock()
+		defer s.b.mu.Unlock()
+
+		delete(s.b.subs, s)
+		close(s.ch)
+	})
+}
+
+/*
+Broadcaster delivers every published value to all the subscribers present at that moment, which
+can join and leave at any time, unlike split whose destinations are fixed up front.
+
+Publish never blocks: each subscriber has its own buffer, and one that falls behind loses its
+oldest values rather than slowing down the publisher or the other subscribers. Lag and Dropped
+show who is falling behind.
+*/
+type Broadcaster[T any] struct {
+	//held for reading while publishing, so a subscriber's channel is never closed under a send.
+	mu     sync.RWMutex
+	subs   map[*Subscriber[T]]struct{}
+	closed bool
+}
+
+// New creates a Broadcaster without subscribers.
+func New[T any]() *Broadcaster[T] {
+	return &Broadcaster[T]{subs: make(map[*Subscriber[T]]struct{})}
+}
+
+// Subscribe adds a subscriber with room for buffer values, it receives the values published
+// from now on. Subscribing to a closed Broadcaster returns a subscriber whose C is already closed.
+func (b *Broadcaster[T]) Subscribe(buffer int) *Subscriber[T] {
+	//at least one, a subscriber without a buffer would lose every value it isn't waiting for.
+	s := &Subscriber[T]{b: b, ch: make(chan T, max(buffer, 1))}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		s.once.Do(func() { close(s.ch) })
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Len returns the number of current subscribers.
+func (b *Broadcaster[T]) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// Publish delivers v to every current subscriber, dropping the oldest buffered value of the
+// ones that are full.
+func (b *Broadcaster[T]) Publish(v T) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subs {
+		for sent := false; !sent; {
+			select {
+			case s.ch <- v:
+				sent = true
+			default:
+				//make room, the subscriber might have read it in the meantime, that's fine too.
+				select {
+				case <-s.ch:
+					s.dropped.Add(1)
+				default:
+				}
+			}
+		}
+	}
+}
+
+// Close makes every subscriber leave, later Subscribe calls get closed subscribers.
+func (b *Broadcaster[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]*Subscriber[T], 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Leave()
+	}
+}
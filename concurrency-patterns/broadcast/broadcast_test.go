@@ -0,0 +1,72 @@
+package broadcast
+
+import (
+	"slices"
+	"testing"
+)
+
+func collect[T any](ch <-chan T) []T {
+	var out []T
+	for v := range ch {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := New[int]()
+
+	early := b.Subscribe(10)
+	b.Publish(1)
+
+	//joins late, so it misses 1.
+	late := b.Subscribe(10)
+	b.Publish(2)
+
+	//leaves early, so it misses 3.
+	leaver := b.Subscribe(10)
+	b.Publish(3)
+	leaver.Leave()
+	b.Publish(4)
+
+	if b.Len() != 2 {
+		t.Errorf("expected 2 subscribers; actual %d", b.Len())
+	}
+	if early.Lag() != 4 {
+		t.Errorf("expected a lag of 4; actual %d", early.Lag())
+	}
+
+	b.Close()
+
+	if got := collect(early.C()); !slices.Equal(got, []int{1, 2, 3, 4}) {
+		t.Errorf("expected [1 2 3 4]; actual %v", got)
+	}
+	if got := collect(late.C()); !slices.Equal(got, []int{2, 3, 4}) {
+		t.Errorf("expected [2 3 4]; actual %v", got)
+	}
+	if got := collect(leaver.C()); !slices.Equal(got, []int{3}) {
+		t.Errorf("expected [3]; actual %v", got)
+	}
+
+	if got := collect(b.Subscribe(1).C()); got != nil {
+		t.Errorf("expected a closed subscriber; actual %v", got)
+	}
+}
+
+func TestBroadcasterSlowSubscriber(t *testing.T) {
+	b := New[int]()
+	slow := b.Subscribe(2)
+
+	for i := range 5 {
+		b.Publish(i)
+	}
+	b.Close()
+
+	if slow.Dropped() != 3 {
+		t.Errorf("expected 3 dropped; actual %d", slow.Dropped())
+	}
+	//only the newest values are kept.
+	if got := collect(slow.C()); !slices.Equal(got, []int{3, 4}) {
+		t.Errorf("expected [3 4]; actual %v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package channels
+
+import (
+	"context"
+	"reflect"
+)
+
+// OrDone forwards the values of ch until ch is closed or ctx is done, so a consumer can range
+// over a channel it doesn't own without also selecting on ctx.
+func OrDone[T any](ctx context.Context, ch <-chan T) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for {
+			v, err := RecvCtx(ctx, ch)
+			if err != nil {
+				return
+			}
+
+			if err := SendCtx(ctx, out, v); err != nil {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Tee copies every value of ch to n channels. The next value is only read from ch once every
+// copy of the current one has been received, so the slowest consumer sets the pace for all of them.
+func Tee[T any](ctx context.Context, ch <-chan T, n int) []<-chan T {
+	chans := make([]chan T, n)
+	outs := make([]<-chan T, n)
+	for i := range n {
+		chans[i] = make(chan T)
+		outs[i] = chans[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, c := range chans {
+				close(c)
+			}
+		}()
+
+		//the first case waits for ctx, the rest send to each copy.
+		cases := make([]reflect.SelectCase, n+1)
+		cases[0] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+
+		for v := range OrDone(ctx, ch) {
+			value := reflect.ValueOf(&v).Elem()
+			for i, c := range chans {
+				cases[i+1] = reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(c), Send: value}
+			}
+
+			//in whatever order the consumers are ready, a served copy is disabled with a nil channel.
+			for range n {
+				chosen, _, _ := reflect.Select(cases)
+				if chosen == 0 {
+					return
+				}
+				cases[chosen].Chan = reflect.Value{}
+			}
+		}
+	}()
+
+	return outs
+}
+
+// Bridge flattens a channel of channels into a single channel, reading each inner channel to
+// its end before moving on to the next one, which keeps the order of a sequence of channels.
+func Bridge[T any](ctx context.Context, chans <-chan (<-chan T)) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for ch := range OrDone(ctx, chans) {
+			for v := range OrDone(ctx, ch) {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,80 @@
+package channels
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func generate(n int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := range n {
+			ch <- i
+		}
+	}()
+	return ch
+}
+
+func TestOrDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	//never closed, OrDone must still end once ctx is done.
+	src := make(chan int)
+	go func() { src <- 1 }()
+
+	out := OrDone(ctx, src)
+	if v := <-out; v != 1 {
+		t.Fatalf("expected 1; actual %d", v)
+	}
+
+	cancel()
+	for range out {
+	}
+}
+
+func TestTee(t *testing.T) {
+	outs := Tee(context.Background(), generate(5), 3)
+
+	var wg sync.WaitGroup
+	results := make([][]int, len(outs))
+	wg.Add(len(outs))
+	for i, ch := range outs {
+		go func() {
+			defer wg.Done()
+			for v := range ch {
+				results[i] = append(results[i], v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	expected := []int{0, 1, 2, 3, 4}
+	for i, r := range results {
+		if !slices.Equal(r, expected) {
+			t.Errorf("%d: expected %v; actual %v", i, expected, r)
+		}
+	}
+}
+
+func TestBridge(t *testing.T) {
+	chans := make(chan (<-chan int))
+	go func() {
+		defer close(chans)
+		for range 3 {
+			chans <- generate(2)
+		}
+	}()
+
+	var actual []int
+	for v := range Bridge(context.Background(), chans) {
+		actual = append(actual, v)
+	}
+
+	expected := []int{0, 1, 0, 1, 0, 1}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected %v; actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,66 @@
+package channels
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned by RecvCtx when the channel is closed.
+var ErrClosed = errors.New("channel closed")
+
+// SendCtx sends v on ch, or returns ctx.Err() if ctx is done first. A ctx that is already done
+// wins even when ch has room, so a cancelled producer stops right away.
+func SendCtx[T any](ctx context.Context, ch chan<- T, v T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case ch <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RecvCtx receives a value from ch, returning ErrClosed once ch is closed and drained, or
+// ctx.Err() if ctx is done first.
+func RecvCtx[T any](ctx context.Context, ch <-chan T) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			return zero, ErrClosed
+		}
+		return v, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Collect receives up to n values from ch, or every value until ch is closed when n <= 0.
+// A closed channel ends it early without an error, a done ctx returns what was received
+// so far along with ctx.Err().
+func Collect[T any](ctx context.Context, ch <-chan T, n int) ([]T, error) {
+	var vals []T
+	if n > 0 {
+		vals = make([]T, 0, n)
+	}
+
+	for n <= 0 || len(vals) < n {
+		v, err := RecvCtx(ctx, ch)
+		if errors.Is(err, ErrClosed) {
+			break
+		}
+		if err != nil {
+			return vals, err
+		}
+		vals = append(vals, v)
+	}
+
+	return vals, nil
+}
@@ -0,0 +1,60 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSendRecvCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch := make(chan int, 1)
+	if err := SendCtx(ctx, ch, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := RecvCtx(ctx, ch); err != nil || v != 1 {
+		t.Fatalf("expected 1; actual %d, %v", v, err)
+	}
+
+	close(ch)
+	if _, err := RecvCtx(ctx, ch); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v; actual %v", ErrClosed, err)
+	}
+
+	//nobody is listening, only ctx can unblock them.
+	cancel()
+	if err := SendCtx(ctx, make(chan int), 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v; actual %v", context.Canceled, err)
+	}
+	if _, err := RecvCtx(ctx, make(chan int)); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v; actual %v", context.Canceled, err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	vals, err := Collect(context.Background(), generate(10), 3)
+	if err != nil || !slices.Equal(vals, []int{0, 1, 2}) {
+		t.Errorf("expected [0 1 2]; actual %v, %v", vals, err)
+	}
+
+	vals, err = Collect(context.Background(), generate(4), 0)
+	if err != nil || !slices.Equal(vals, []int{0, 1, 2, 3}) {
+		t.Errorf("expected [0 1 2 3]; actual %v, %v", vals, err)
+	}
+
+	ch := make(chan int)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		//both sends are received by Collect, then it's left waiting for the rest.
+		ch <- 1
+		ch <- 2
+		cancel()
+	}()
+
+	vals, err = Collect(ctx, ch, 5)
+	if !errors.Is(err, context.Canceled) || !slices.Equal(vals, []int{1, 2}) {
+		t.Errorf("expected [1 2] and %v; actual %v, %v", context.Canceled, vals, err)
+	}
+}
@@ -0,0 +1,113 @@
+package channels
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// All ranges over the values of ch until it's closed, for code written against iter.Seq.
+func All[T any](ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Merge is fan-in as an iterator: it yields the values of every source as they arrive until all
+// of them are closed or ctx is done. Breaking out of the loop stops the goroutines reading the
+// sources right away.
+func Merge[T any](ctx context.Context, sources ...<-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+
+		merged := make(chan T)
+		var wg sync.WaitGroup
+		wg.Add(len(sources))
+		for _, src := range sources {
+			go func() {
+				defer wg.Done()
+				for v := range OrDone(ctx, src) {
+					select {
+					case merged <- v:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(merged)
+		}()
+
+		//on an early break, stop the forwarders and wait for them, so nothing is left running.
+		defer func() {
+			cancel()
+			for range merged {
+			}
+		}()
+
+		for v := range merged {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Chan is the other way around: it sends the values of seq on a channel, e.g. to fan them out
+// with Tee, until seq is exhausted or ctx is done.
+func Chan[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for v := range seq {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Map yields fn applied to every value of seq.
+func Map[In, Out any](seq iter.Seq[In], fn func(In) Out) iter.Seq[Out] {
+	return func(yield func(Out) bool) {
+		for v := range seq {
+			if !yield(fn(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Batch yields the values of seq in slices of size, the last one can be shorter.
+func Batch[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		batch := make([]T, 0, size)
+		for v := range seq {
+			batch = append(batch, v)
+			if len(batch) < size {
+				continue
+			}
+			if !yield(batch) {
+				return
+			}
+			//the caller might keep the slice, so start over with a new one.
+			batch = make([]T, 0, size)
+		}
+
+		if len(batch) > 0 {
+			yield(batch)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package channels
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	merged := slices.Collect(Merge(context.Background(), generate(3), generate(2)))
+	slices.Sort(merged)
+
+	if !slices.Equal(merged, []int{0, 0, 1, 1, 2}) {
+		t.Errorf("expected [0 0 1 1 2]; actual %v", merged)
+	}
+}
+
+func TestMergeBreak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	//endless sources, only the break can stop them.
+	endless := func() <-chan int {
+		ch := make(chan int)
+		go func() {
+			for i := 0; ; i++ {
+				ch <- i
+			}
+		}()
+		return ch
+	}
+	a, b := endless(), endless()
+
+	n := 0
+	for range Merge(context.Background(), a, b) {
+		n++
+		if n == 10 {
+			break
+		}
+	}
+
+	//the two producers stay blocked, every goroutine of Merge is gone.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before+2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected Merge to clean up; %d goroutines left over", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMapBatch(t *testing.T) {
+	ctx := context.Background()
+	double := Map(All(generate(5)), func(n int) int { return n * 2 })
+
+	var batches [][]int
+	for b := range Batch(All(Chan(ctx, double)), 2) {
+		batches = append(batches, b)
+	}
+
+	expected := [][]int{{0, 2}, {4, 6}, {8}}
+	if !slices.EqualFunc(batches, expected, slices.Equal) {
+		t.Errorf("expected %v; actual %v", expected, batches)
+	}
+}
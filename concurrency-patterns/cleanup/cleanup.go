@@ -0,0 +1,141 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+type trackerKey struct{}
+
+type registration struct {
+	name string
+	fn   func() error
+}
+
+/*
+Tracker guarantees the cleanup of the resources tied to a context, e.g. the files, child conns and
+tickers a connection handler opens. Components register a closer as they acquire a resource and
+release the registration when they close it themselves. Whatever is still registered when the ctx
+is done is closed by the tracker, in reverse order of registration, like defer.
+
+Every registration the tracker had to clean up itself is reported by Leaked, so a test can assert
+that its components close what they open.
+*/
+type Tracker struct {
+	mu sync.Mutex
+	//ids grow with every Add, they give the order of registration.
+	next   int
+	active map[int]registration
+	closed bool
+	//closed once the first Close ran every registration.
+	done   chan struct{}
+	leaked []string
+	err    error
+	stop   func() bool
+}
+
+// New returns a Tracker attached to ctx: it's cleaned up once ctx is done and FromContext finds
+// it in the returned ctx.
+func New(ctx context.Context) (*Tracker, context.Context) {
+	t := &Tracker{active: make(map[int]registration), done: make(chan struct{})}
+	t.stop = context.AfterFunc(ctx, func() { _ = t.Close() })
+	return t, context.WithValue(ctx, trackerKey{}, t)
+}
+
+// FromContext returns the Tracker attached to ctx by New, or nil.
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
+
+// Add registers fn under name and returns the function that releases the registration once
+// the owner cleaned up by itself. On a tracker that's already closed fn runs right away.
+func (t *Tracker) Add(name string, fn func() error) (release func()) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		err := fn()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.leaked = append(t.leaked, name)
+		if err != nil {
+			t.err = errors.Join(t.err, fmt.Errorf("%s: %w", name, err))
+		}
+		return func() {}
+	}
+
+	id := t.next
+	t.next++
+	t.active[id] = registration{name: name, fn: fn}
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.active, id)
+	}
+}
+
+// AddCloser is Add for an io.Closer.
+func (t *Tracker) AddCloser(name string, c io.Closer) (release func()) {
+	return t.Add(name, c.Close)
+}
+
+// Close runs every registration still active, last registered first, and returns their errors
+// joined. It runs once, later calls wait for the first one to finish and return the same error.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		<-t.done
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return t.err
+	}
+	t.closed = true
+	t.stop()
+
+	ids := make([]int, 0, len(t.active))
+	for id := range t.active {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	pending := make([]registration, 0, len(ids))
+	for _, id := range slices.Backward(ids) {
+		pending = append(pending, t.active[id])
+	}
+	t.active = nil
+	t.mu.Unlock()
+	defer close(t.done)
+
+	//outside the lock, a closer might register or release something itself.
+	var errs []error
+	names := make([]string, 0, len(pending))
+	for _, r := range pending {
+		names = append(names, r.name)
+		if err := r.fn(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.name, err))
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.leaked = append(t.leaked, names...)
+	t.err = errors.Join(append([]error{t.err}, errs...)...)
+	return t.err
+}
+
+// Leaked returns the names of the registrations the tracker had to clean up because their owner
+// never released them.
+func (t *Tracker) Leaked() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.leaked...)
+}
@@ -0,0 +1,103 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tr, ctx := New(ctx)
+
+	if FromContext(ctx) != tr {
+		t.Fatal("expected the tracker to be attached to ctx")
+	}
+
+	var order []string
+	closer := func(name string, err error) func() error {
+		return func() error {
+			order = append(order, name)
+			return err
+		}
+	}
+
+	errTicker := errors.New("ticker")
+	tr.Add("file", closer("file", nil))
+	release := tr.Add("child conn", closer("child conn", nil))
+	tr.Add("ticker", closer("ticker", errTicker))
+
+	//the owner closed this one itself.
+	release()
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for len(tr.Leaked()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the tracker to clean up once ctx is done")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if !slices.Equal(order, []string{"ticker", "file"}) {
+		t.Errorf("expected LIFO [ticker file]; actual %v", order)
+	}
+	if !slices.Equal(tr.Leaked(), []string{"ticker", "file"}) {
+		t.Errorf("expected [ticker file] to be reported; actual %v", tr.Leaked())
+	}
+	if err := tr.Close(); !errors.Is(err, errTicker) {
+		t.Errorf("expected %v; actual %v", errTicker, err)
+	}
+}
+
+func TestTrackerAddAfterClose(t *testing.T) {
+	tr, _ := New(context.Background())
+	if err := tr.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := false
+	tr.Add("late", func() error {
+		closed = true
+		return nil
+	})
+
+	if !closed {
+		t.Error("expected a registration on a closed tracker to be cleaned up right away")
+	}
+}
+
+func TestTrackerConcurrentClose(t *testing.T) {
+	tr, _ := New(context.Background())
+
+	errSlow := errors.New("slow")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	tr.Add("slow", func() error {
+		close(started)
+		<-release
+		return errSlow
+	})
+
+	first := make(chan error, 1)
+	go func() { first <- tr.Close() }()
+	<-started
+
+	//a second Close waits for the closers of the first one.
+	second := make(chan error, 1)
+	go func() { second <- tr.Close() }()
+	select {
+	case err := <-second:
+		t.Fatalf("expected Close to wait for the first one; returned %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(release)
+	for _, ch := range []chan error{first, second} {
+		if err := <-ch; !errors.Is(err, errSlow) {
+			t.Errorf("expected %v; actual %v", errSlow, err)
+		}
+	}
+}
@@ -1,13 +1,81 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 )
 
-func funnel(sources ...<-chan int) <-chan int {
-	dest := make(chan int)
+type funnelOptions struct {
+	drain bool
+}
+
+// funnelOption configures funnel and orderedFunnel.
+type funnelOption func(*funnelOptions)
+
+// withDrain keeps reading the sources after ctx is done and discards what they send, so their
+// producers can finish and close them instead of blocking forever on a send nobody receives.
+func withDrain() funnelOption {
+	return func(o *funnelOptions) {
+		o.drain = true
+	}
+}
+
+func newFunnelOptions(opts []funnelOption) funnelOptions {
+	var o funnelOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// drainAll discards what is left in sources until they're closed, each on its own goroutine.
+func drainAll[T any](sources []<-chan T) {
+	for _, ch := range sources {
+		go func() {
+			for range ch {
+			}
+		}()
+	}
+}
+
+// recv receives from ch unless ctx is done first, ok is false in both cases it gives up.
+func recv[T any](ctx context.Context, ch <-chan T) (T, bool) {
+	//checked first, select alone would keep receiving at random while ch still has values.
+	if ctx.Err() != nil {
+		var zero T
+		return zero, false
+	}
+
+	select {
+	case v, ok := <-ch:
+		return v, ok
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	}
+}
+
+// send sends v on ch unless ctx is done first, and reports whether it did.
+func send[T any](ctx context.Context, ch chan<- T, v T) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// funnel merges sources into one channel, closed once every source is closed or ctx is done.
+// The sources are left as they are once ctx is done, unless withDrain keeps them flowing.
+func funnel[T any](ctx context.Context, sources []<-chan T, opts ...funnelOption) <-chan T {
+	o := newFunnelOptions(opts)
+	dest := make(chan T)
 	var wg sync.WaitGroup
 
 	wg.Add(len(sources))
@@ -15,8 +83,20 @@ func funnel(sources ...<-chan int) <-chan int {
 	for _, ch := range sources {
 		go func() {
 			defer wg.Done()
-			for n := range ch {
-				dest <- n
+			for {
+				v, ok := recv(ctx, ch)
+				if !ok {
+					break
+				}
+				if !send(ctx, dest, v) {
+					break
+				}
+			}
+
+			//gave up on dest, what's left is discarded so the producer can finish.
+			if o.drain {
+				for range ch {
+				}
 			}
 		}()
 	}
@@ -30,6 +110,70 @@ func funnel(sources ...<-chan int) <-chan int {
 	return dest
 }
 
+// orderedFunnel merges sources into one channel ordered by key, e.g. a sequence number or a
+// timestamp in UnixNano. Every source has to be ordered by key already, which holds after a
+// fan-out whose workers keep the order they receive items in. Each source is buffered one item
+// deep: nothing is sent until every open source has an item ready, or is closed, so the smallest one is known.
+// dest is closed once every source is closed or ctx is done, withDrain keeps the sources flowing
+// after that.
+func orderedFunnel[T any](ctx context.Context, key func(T) int64, sources []<-chan T, opts ...funnelOption) <-chan T {
+	o := newFunnelOptions(opts)
+	dest := make(chan T)
+
+	go func() {
+		defer close(dest)
+		if o.drain {
+			//runs once ctx is done or every source is closed, the closed ones return right away.
+			defer drainAll(sources)
+		}
+
+		//next item of each source, nil once it's taken and not replaced yet.
+		heads := make([]*T, len(sources))
+		open := make([]bool, len(sources))
+		for i := range open {
+			open[i] = true
+		}
+
+		for {
+			//fill the heads of the open sources.
+			for i, ch := range sources {
+				if !open[i] || heads[i] != nil {
+					continue
+				}
+				v, ok := recv(ctx, ch)
+				if ctx.Err() != nil {
+					return
+				}
+				if ok {
+					heads[i] = &v
+				} else {
+					open[i] = false
+				}
+			}
+
+			//pick the smallest head.
+			next := -1
+			for i, h := range heads {
+				if h != nil && (next == -1 || key(*h) < key(*heads[next])) {
+					next = i
+				}
+			}
+
+			//every source is closed and drained.
+			if next == -1 {
+				return
+			}
+
+			if !send(ctx, dest, *heads[next]) {
+				return
+			}
+			heads[next] = nil
+		}
+	}()
+
+	return dest
+}
+
 func main() {
 	sources := make([]<-chan int, 0)
 
@@ -47,7 +191,7 @@ func main() {
 
 	}
 
-	dest := funnel(sources...)
+	dest := funnel(context.Background(), sources)
 	for n := range dest {
 		fmt.Println(n)
 	}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOrderedFunnel(t *testing.T) {
+	type item struct {
+		seq int64
+	}
+
+	//a round robin fan-out of 0..11 over 3 workers, each keeps its order.
+	sources := make([]<-chan item, 0, 3)
+	for w := range 3 {
+		ch := make(chan item)
+		sources = append(sources, ch)
+
+		go func() {
+			defer close(ch)
+			for seq := int64(w); seq < 12; seq += 3 {
+				ch <- item{seq}
+			}
+		}()
+	}
+
+	var actual []int64
+	for it := range orderedFunnel(context.Background(), func(it item) int64 { return it.seq }, sources) {
+		actual = append(actual, it.seq)
+	}
+
+	expected := []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected %v; actual %v", expected, actual)
+	}
+}
+
+func TestFunnelCancel(t *testing.T) {
+	tests := []struct {
+		name  string
+		drain bool
+	}{
+		{name: "stop"},
+		{name: "drain", drain: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			src := make(chan int)
+			//closed once the producer managed to send everything.
+			produced := make(chan struct{})
+			go func() {
+				defer close(src)
+				for i := range 5 {
+					select {
+					case src <- i:
+					case <-time.After(time.Millisecond * 100):
+						return
+					}
+				}
+				close(produced)
+			}()
+
+			var opts []funnelOption
+			if tt.drain {
+				opts = append(opts, withDrain())
+			}
+
+			dest := funnel(ctx, []<-chan int{src}, opts...)
+			<-dest
+			//the consumer goes away.
+			cancel()
+
+			//dest is closed even though nobody reads it anymore.
+			for range dest {
+			}
+
+			select {
+			case <-produced:
+				if !tt.drain {
+					t.Error("expected the producer to be stuck without drain")
+				}
+			case <-time.After(time.Millisecond * 500):
+				if tt.drain {
+					t.Error("expected drain to consume the rest of the source")
+				}
+			}
+		})
+	}
+}
+
+func TestOrderedFunnelCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	//a source that never closes and one that is never read to the end.
+	idle := make(chan int64)
+	busy := make(chan int64)
+	produced := make(chan struct{})
+	go func() {
+		defer close(busy)
+		for i := range int64(5) {
+			busy <- i
+		}
+		close(produced)
+	}()
+
+	dest := orderedFunnel(ctx, func(v int64) int64 { return v }, []<-chan int64{idle, busy}, withDrain())
+
+	//blocked on the idle source, cancelling must still close dest.
+	cancel()
+	select {
+	case _, ok := <-dest:
+		if ok {
+			t.Error("expected nothing to be sent after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected dest to be closed once ctx is done")
+	}
+
+	select {
+	case <-produced:
+	case <-time.After(time.Second):
+		t.Error("expected drain to consume the rest of the source")
+	}
+}
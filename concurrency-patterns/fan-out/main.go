@@ -1,34 +1,281 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"sync"
 )
 
-func split(source <-chan int, n int) []<-chan int {
-	dests := make([]<-chan int, 0, n)
+// errNoDestinations is returned by stop when split, partition or distribute is asked for fewer
+// than one destination, no value is ever read from source then.
+var errNoDestinations = errors.New("fan-out needs at least one destination")
 
-	for range n {
-		ch := make(chan int)
+// noDestinations is what split and route return for n < 1.
+func noDestinations() func() error {
+	return func() error { return errNoDestinations }
+}
+
+type splitOptions struct {
+	//buffer of every destination without its own size.
+	buffer int
+	//per destination buffer sizes, by index.
+	buffers []int
+}
+
+// splitOption configures split.
+type splitOption func(*splitOptions)
+
+// withBuffer gives every destination a buffer of size values, so a slow consumer doesn't stall
+// its producer on every value. Destinations are unbuffered by default.
+func withBuffer(size int) splitOption {
+	return func(o *splitOptions) {
+		o.buffer = size
+	}
+}
+
+// withBuffers sets the buffer of each destination by index, destinations past the end of sizes
+// fall back to withBuffer.
+func withBuffers(sizes ...int) splitOption {
+	return func(o *splitOptions) {
+		o.buffers = sizes
+	}
+}
+
+func (o splitOptions) bufferOf(i int) int {
+	if i < len(o.buffers) {
+		return o.buffers[i]
+	}
+	return o.buffer
+}
+
+func split[T any](ctx context.Context, source <-chan T, n int, opts ...splitOption) (dests []<-chan T, stop func() error) {
+	if n < 1 {
+		return nil, noDestinations()
+	}
+
+	var o splitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	dests = make([]<-chan T, 0, n)
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	errs := make(chan error, n)
+
+	for i := range n {
+		ch := make(chan T, o.bufferOf(i))
 		dests = append(dests, ch)
 
 		//create a goroutine that is going to do the send
 		go func() {
+			defer wg.Done()
 			defer close(ch)
 
-			for val := range source {
-				ch <- val
-			}
+			for {
+				select {
+				case val, ok := <-source:
+					if !ok {
+						return
+					}
 
+					select {
+					case ch <- val:
+					case <-ctx.Done():
+						errs <- ctx.Err()
+						return
+					}
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
 		}()
 	}
 
-	return dests
+	stop = sync.OnceValue(func() error {
+		cancel()
+		wg.Wait()
+		close(errs)
+		//every producer reports the same ctx error, the first one is enough.
+		return <-errs
+	})
+
+	return dests, stop
+}
+
+// partition is split where every value goes to the destination picked by hashing its key, so
+// all values of the same key land on the same consumer, in the order they were read from source.
+// A single goroutine routes every value, so a consumer that falls behind holds up the others
+// once its buffer is full. stop works like split's.
+func partition[T any](ctx context.Context, source <-chan T, n int, key func(T) string, opts ...splitOption) (dests []<-chan T, stop func() error) {
+	dests, _, stop = route(ctx, source, n, func(val T, depths func() []int) int {
+		return shard(key(val), n)
+	}, opts)
+	return dests, stop
+}
+
+// strategy picks the destination of the next value from the current depth of every destination.
+type strategy func(depths []int) int
+
+// roundRobin sends values to the destinations in turn.
+func roundRobin() strategy {
+	next := 0
+	return func(depths []int) int {
+		i := next % len(depths)
+		next++
+		return i
+	}
+}
+
+// leastPending sends each value to the destination with the fewest values waiting in its buffer,
+// ties go round robin. Unbuffered destinations are always empty, so it only makes sense with withBuffer.
+func leastPending() strategy {
+	rr := roundRobin()
+	return func(depths []int) int {
+		start := rr(depths)
+		best := start
+		for j := range depths {
+			i := (start + j) % len(depths)
+			if depths[i] < depths[best] {
+				best = i
+			}
+		}
+		return best
+	}
+}
+
+// weighted sends each destination a share of the values proportional to its weight, spread
+// evenly rather than in bursts (smooth weighted round robin, as nginx does it). weights are
+// fitted to the number of destinations: the ones without a weight get 1, extra weights are ignored.
+func weighted(weights ...int) strategy {
+	var current []int
+	total := 0
+
+	return func(depths []int) int {
+		if len(current) != len(depths) {
+			weights = fit(weights, len(depths))
+			current = make([]int, len(weights))
+			total = 0
+			for _, w := range weights {
+				total += w
+			}
+		}
+
+		best := 0
+		for i, w := range weights {
+			current[i] += w
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		return best
+	}
+}
+
+// fit resizes weights to n, padding with a weight of 1.
+func fit(weights []int, n int) []int {
+	fitted := make([]int, n)
+	for i := range fitted {
+		fitted[i] = 1
+		if i < len(weights) {
+			fitted[i] = weights[i]
+		}
+	}
+	return fitted
+}
+
+// distribute is split where strategy picks the destination of every value instead of whichever
+// consumer reads first. depths returns how many values are waiting in each destination's buffer,
+// to make skew between the consumers visible. stop works like split's.
+func distribute[T any](ctx context.Context, source <-chan T, n int, strategy strategy, opts ...splitOption) (dests []<-chan T, depths func() []int, stop func() error) {
+	return route(ctx, source, n, func(val T, depths func() []int) int {
+		return strategy(depths())
+	}, opts)
+}
+
+// route is the single routing goroutine behind partition and distribute, pick chooses the
+// destination of every value. With n < 1 there is nothing to route to, see errNoDestinations.
+func route[T any](ctx context.Context, source <-chan T, n int, pick func(val T, depths func() []int) int, opts []splitOption) (dests []<-chan T, depths func() []int, stop func() error) {
+	if n < 1 {
+		return nil, func() []int { return nil }, noDestinations()
+	}
+
+	var o splitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	chans := make([]chan T, 0, n)
+	dests = make([]<-chan T, 0, n)
+	for i := range n {
+		ch := make(chan T, o.bufferOf(i))
+		chans = append(chans, ch)
+		dests = append(dests, ch)
+	}
+
+	depths = func() []int {
+		d := make([]int, len(chans))
+		for i, ch := range chans {
+			d[i] = len(ch)
+		}
+		return d
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			for _, ch := range chans {
+				close(ch)
+			}
+		}()
+
+		for {
+			select {
+			case val, ok := <-source:
+				if !ok {
+					done <- nil
+					return
+				}
+
+				select {
+				case chans[pick(val, depths)] <- val:
+				case <-ctx.Done():
+					done <- ctx.Err()
+					return
+				}
+			case <-ctx.Done():
+				done <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	stop = sync.OnceValue(func() error {
+		cancel()
+		return <-done
+	})
+
+	return dests, depths, stop
+}
+
+// shard maps key to one of n destinations.
+func shard(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
 }
 
 func main() {
 	source := make(chan int)
-	dests := split(source, 5)
+	dests, stop := split(context.Background(), source, 5)
+	defer stop()
 
 	go func() {
 
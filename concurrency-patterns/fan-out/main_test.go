@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitDrained(t *testing.T) {
+	source := make(chan int, 10)
+	for i := range 10 {
+		source <- i
+	}
+	close(source)
+
+	dests, stop := split(context.Background(), source, 3)
+
+	sum := 0
+	for _, ch := range dests {
+		for val := range ch {
+			sum += val
+		}
+	}
+
+	if sum != 45 {
+		t.Errorf("expected 45; actual %d", sum)
+	}
+	if err := stop(); err != nil {
+		t.Errorf("expected no error after draining; actual %v", err)
+	}
+}
+
+func TestSplitAbandonedConsumer(t *testing.T) {
+	//never closed, and nobody reads the destinations.
+	source := make(chan int, 1)
+	source <- 1
+
+	dests, stop := split(context.Background(), source, 2)
+
+	if err := stop(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v; actual %v", context.Canceled, err)
+	}
+
+	//every destination is closed once the producers are gone.
+	for _, ch := range dests {
+		for range ch {
+		}
+	}
+}
+
+func TestSplitBuffers(t *testing.T) {
+	source := make(chan string)
+	dests, stop := split(context.Background(), source, 3, withBuffer(4), withBuffers(1, 2))
+	defer stop()
+
+	expected := []int{1, 2, 4}
+	for i, ch := range dests {
+		if cap(ch) != expected[i] {
+			t.Errorf("%d: expected a buffer of %d; actual %d", i, expected[i], cap(ch))
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	type item struct {
+		key string
+		seq int
+	}
+
+	source := make(chan item)
+	go func() {
+		defer close(source)
+		for seq := range 100 {
+			source <- item{key: fmt.Sprintf("key-%d", seq%7), seq: seq}
+		}
+	}()
+
+	dests, stop := partition(context.Background(), source, 4, func(it item) string { return it.key })
+
+	var mu sync.Mutex
+	//destination of each key and the last seq seen for it.
+	owner := make(map[string]int)
+	last := make(map[string]int)
+
+	var wg sync.WaitGroup
+	wg.Add(len(dests))
+	for i, ch := range dests {
+		go func() {
+			defer wg.Done()
+			for it := range ch {
+				mu.Lock()
+				if o, ok := owner[it.key]; ok && o != i {
+					t.Errorf("%s: expected destination %d; actual %d", it.key, o, i)
+				}
+				if l, ok := last[it.key]; ok && l > it.seq {
+					t.Errorf("%s: %d received after %d", it.key, it.seq, l)
+				}
+				owner[it.key] = i
+				last[it.key] = it.seq
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(owner) != 7 {
+		t.Errorf("expected 7 keys; actual %d", len(owner))
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy strategy
+		depths   []int
+		expected []int
+	}{
+		{name: "round robin", strategy: roundRobin(), depths: []int{0, 0, 0}, expected: []int{0, 1, 2, 0, 1, 2}},
+		{name: "least pending", strategy: leastPending(), depths: []int{3, 0, 2}, expected: []int{1, 1, 1, 1}},
+		{name: "weighted", strategy: weighted(5, 1, 1), depths: []int{0, 0, 0}, expected: []int{0, 0, 1, 0, 2, 0, 0}},
+		{name: "weighted missing weights", strategy: weighted(2), depths: []int{0, 0, 0}, expected: []int{0, 1, 2, 0}},
+		{name: "weighted extra weights", strategy: weighted(1, 1, 5), depths: []int{0, 0}, expected: []int{0, 1, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := make([]int, 0, len(tt.expected))
+			for range tt.expected {
+				actual = append(actual, tt.strategy(tt.depths))
+			}
+			if !slices.Equal(actual, tt.expected) {
+				t.Errorf("expected %v; actual %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDistributeDepths(t *testing.T) {
+	source := make(chan int)
+	dests, depths, stop := distribute(context.Background(), source, 2, roundRobin(), withBuffer(5))
+
+	for i := range 6 {
+		source <- i
+	}
+	close(source)
+
+	//nobody read yet, so the values all end up buffered.
+	deadline := time.Now().Add(time.Second)
+	for d := depths(); !slices.Equal(d, []int{3, 3}); d = depths() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected [3 3]; actual %v", d)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i, ch := range dests {
+		for val := range ch {
+			if val%2 != i {
+				t.Errorf("%d: unexpected value %d", i, val)
+			}
+		}
+	}
+
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoDestinations(t *testing.T) {
+	source := make(chan int, 1)
+	source <- 1
+
+	dests, stop := split(context.Background(), source, 0)
+	if len(dests) != 0 || !errors.Is(stop(), errNoDestinations) {
+		t.Errorf("split: expected %v; actual %d destinations, %v", errNoDestinations, len(dests), stop())
+	}
+
+	dests, stop = partition(context.Background(), source, 0, func(v int) string { return "k" })
+	if len(dests) != 0 || !errors.Is(stop(), errNoDestinations) {
+		t.Errorf("partition: expected %v; actual %d destinations, %v", errNoDestinations, len(dests), stop())
+	}
+
+	dests, depths, stop := distribute(context.Background(), source, 0, roundRobin())
+	if len(dests) != 0 || len(depths()) != 0 || !errors.Is(stop(), errNoDestinations) {
+		t.Errorf("distribute: expected %v; actual %d destinations, %v", errNoDestinations, len(dests), stop())
+	}
+
+	//nothing was read from source.
+	if len(source) != 1 {
+		t.Error("expected source to be left alone")
+	}
+}
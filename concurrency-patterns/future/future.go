@@ -0,0 +1,158 @@
+package future
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+
+	"networking/stablity-patterns/recovery"
+)
+
+// ErrNoFutures is the error of Any when it's given no futures, there is no result to wait for.
+var ErrNoFutures = errors.New("no futures")
+
+/*
+Future is the result of a computation running on its own goroutine, a typed replacement for the
+resCh/errCh pairs otherwise created by hand for every call, e.g. in stablity-patterns/timeout.
+
+The result is set once and can be read any number of times, by any number of goroutines, with Get.
+*/
+type Future[T any] struct {
+	done chan struct{}
+	once sync.Once
+	val  T
+	err  error
+}
+
+// New runs fn with ctx on a new goroutine and returns its Future. A panic in fn becomes a
+// *recovery.PanicError.
+func New[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) *Future[T] {
+	f, resolve := NewPromise[T]()
+
+	go func() {
+		var val T
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				err = &recovery.PanicError{Value: r, Stack: debug.Stack()}
+			}
+			resolve(val, err)
+		}()
+
+		val, err = fn(ctx)
+	}()
+
+	return f
+}
+
+// NewPromise returns a Future completed by calling resolve, for results produced by callbacks
+// rather than by a function. Only the first call of resolve counts.
+func NewPromise[T any]() (f *Future[T], resolve func(val T, err error)) {
+	f = &Future[T]{done: make(chan struct{})}
+	return f, f.resolve
+}
+
+func (f *Future[T]) resolve(val T, err error) {
+	f.once.Do(func() {
+		f.val, f.err = val, err
+		close(f.done)
+	})
+}
+
+// Done is closed once the result is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get blocks until the result is available or ctx is done. Giving up doesn't stop the computation.
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Then runs fn with the result of f once it succeeds, an error of f is passed on without calling fn.
+// It's a function and not a method because methods can't introduce the type parameter U.
+func Then[T, U any](ctx context.Context, f *Future[T], fn func(ctx context.Context, val T) (U, error)) *Future[U] {
+	return New(ctx, func(ctx context.Context) (U, error) {
+		val, err := f.Get(ctx)
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+		return fn(ctx, val)
+	})
+}
+
+// All completes with the results of every future in order, or with the first error.
+func All[T any](ctx context.Context, fs ...*Future[T]) *Future[[]T] {
+	return New(ctx, func(ctx context.Context) ([]T, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		//gather the errors as they come, so a late future doesn't hide an early failure.
+		errCh := make(chan error, len(fs))
+		for _, f := range fs {
+			go func() {
+				_, err := f.Get(ctx)
+				errCh <- err
+			}()
+		}
+
+		for range fs {
+			if err := <-errCh; err != nil {
+				return nil, err
+			}
+		}
+
+		vals := make([]T, len(fs))
+		for i, f := range fs {
+			vals[i] = f.val
+		}
+		return vals, nil
+	})
+}
+
+// Any completes with the first successful result, or with every error joined once all of them
+// failed. Without futures it fails with ErrNoFutures.
+func Any[T any](ctx context.Context, fs ...*Future[T]) *Future[T] {
+	return New(ctx, func(ctx context.Context) (T, error) {
+		if len(fs) == 0 {
+			var zero T
+			return zero, ErrNoFutures
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			val T
+			err error
+		}
+
+		resCh := make(chan result, len(fs))
+		for _, f := range fs {
+			go func() {
+				val, err := f.Get(ctx)
+				resCh <- result{val, err}
+			}()
+		}
+
+		errs := make([]error, 0, len(fs))
+		for range fs {
+			r := <-resCh
+			if r.err == nil {
+				return r.val, nil
+			}
+			errs = append(errs, r.err)
+		}
+
+		var zero T
+		return zero, errors.Join(errs...)
+	})
+}
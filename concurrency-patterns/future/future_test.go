@@ -0,0 +1,91 @@
+package future
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"networking/stablity-patterns/recovery"
+)
+
+func value[T any](v T, err error) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) { return v, err }
+}
+
+func TestFuture(t *testing.T) {
+	ctx := context.Background()
+
+	f := Then(ctx, New(ctx, value(21, nil)), func(ctx context.Context, n int) (string, error) {
+		return strconv.Itoa(n * 2), nil
+	})
+
+	s, err := f.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "42" {
+		t.Errorf("expected 42; actual %s", s)
+	}
+
+	_, err = New(ctx, func(ctx context.Context) (int, error) { panic("boom") }).Get(ctx)
+	if !errors.Is(err, recovery.ErrPanic) {
+		t.Errorf("expected %v; actual %v", recovery.ErrPanic, err)
+	}
+}
+
+func TestGetCancel(t *testing.T) {
+	f, _ := NewPromise[int]()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	if _, err := f.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestAll(t *testing.T) {
+	ctx := context.Background()
+	errBoom := errors.New("boom")
+
+	vals, err := All(ctx, New(ctx, value(1, nil)), New(ctx, value(2, nil))).Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(vals, []int{1, 2}) {
+		t.Errorf("expected [1 2]; actual %v", vals)
+	}
+
+	//the failure is reported without waiting for the future that never completes.
+	never, _ := NewPromise[int]()
+	if _, err := All(ctx, never, New(ctx, value(0, errBoom))).Get(ctx); !errors.Is(err, errBoom) {
+		t.Errorf("expected %v; actual %v", errBoom, err)
+	}
+}
+
+func TestAny(t *testing.T) {
+	ctx := context.Background()
+	errA := errors.New("a")
+	errB := errors.New("b")
+
+	never, _ := NewPromise[int]()
+	v, err := Any(ctx, never, New(ctx, value(0, errA)), New(ctx, value(3, nil))).Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 3 {
+		t.Errorf("expected 3; actual %d", v)
+	}
+
+	_, err = Any(ctx, New(ctx, value(0, errA)), New(ctx, value(0, errB))).Get(ctx)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("expected both errors; actual %v", err)
+	}
+
+	if _, err := Any[int](ctx).Get(ctx); !errors.Is(err, ErrNoFutures) {
+		t.Errorf("expected %v; actual %v", ErrNoFutures, err)
+	}
+}
@@ -0,0 +1,153 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+
+	"networking/stablity-patterns/recovery"
+)
+
+type options struct {
+	limit    int
+	failFast bool
+}
+
+// Option configures a Group.
+type Option func(*options)
+
+// WithLimit caps the number of tasks running at the same time, Go blocks until one finishes.
+// By default there is no limit.
+func WithLimit(n int) Option {
+	return func(o *options) {
+		o.limit = n
+	}
+}
+
+// FailFast cancels the ctx of the group, and so of every task, on the first error. By default
+// the other tasks keep running and all their errors are collected.
+func FailFast() Option {
+	return func(o *options) {
+		o.failFast = true
+	}
+}
+
+/*
+Group runs a set of tasks on their own goroutines and waits for all of them, like errgroup, but
+Wait returns every error instead of the first one, a panicking task is turned into a
+*recovery.PanicError instead of crashing the process, and WithLimit bounds how many tasks run at once.
+
+Every task gets its own ctx, derived from the group's and cancelled as soon as the task returns, so
+whatever it started with that ctx is cleaned up with it.
+*/
+type Group struct {
+	opts   options
+	ctx    context.Context
+	cancel context.CancelFunc
+	//one slot per running task when there is a limit.
+	sem chan struct{}
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// New creates a Group whose tasks run with ctx. The returned ctx is cancelled when Wait returns,
+// or on the first error with FailFast.
+func New(ctx context.Context, opts ...Option) (*Group, context.Context) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	g := &Group{
+		opts:   o,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if o.limit > 0 {
+		g.sem = make(chan struct{}, o.limit)
+	}
+
+	return g, ctx
+}
+
+// Go runs fn on a new goroutine, blocking first while the group is at its limit.
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(fn)
+}
+
+// GoContext is Go giving up on waiting for a free slot once ctx is done, it returns ctx.Err()
+// without running fn then.
+func (g *Group) GoContext(ctx context.Context, fn func(ctx context.Context) error) error {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	g.start(fn)
+	return nil
+}
+
+// TryGo runs fn only if the group is below its limit, and reports whether it did.
+func (g *Group) TryGo(fn func(ctx context.Context) error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(fn)
+	return true
+}
+
+// Wait blocks until every task has returned and returns all their errors joined, nil if none failed.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
+
+func (g *Group) start(fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+
+		if err := g.run(fn); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+
+			if g.opts.failFast {
+				g.cancel()
+			}
+		}
+	}()
+}
+
+func (g *Group) run(fn func(ctx context.Context) error) (err error) {
+	ctx, cancel := context.WithCancel(g.ctx)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &recovery.PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return fn(ctx)
+}
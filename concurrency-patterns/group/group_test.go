@@ -0,0 +1,105 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"networking/stablity-patterns/recovery"
+)
+
+func TestGroupCollectsErrors(t *testing.T) {
+	g, _ := New(context.Background())
+
+	errA := errors.New("a")
+	errB := errors.New("b")
+	g.Go(func(ctx context.Context) error { return errA })
+	g.Go(func(ctx context.Context) error { return errB })
+	g.Go(func(ctx context.Context) error { return nil })
+	g.Go(func(ctx context.Context) error { panic("boom") })
+
+	err := g.Wait()
+	for _, e := range []error{errA, errB, recovery.ErrPanic} {
+		if !errors.Is(err, e) {
+			t.Errorf("expected %v in %v", e, err)
+		}
+	}
+}
+
+func TestGroupLimit(t *testing.T) {
+	g, _ := New(context.Background(), WithLimit(2))
+
+	var running, peak atomic.Int64
+	release := make(chan struct{})
+	for range 6 {
+		g.Go(func(ctx context.Context) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+			return nil
+		})
+
+		//once the group is full nothing more fits, let one of them finish.
+		if g.TryGo(func(ctx context.Context) error { return nil }) {
+			continue
+		}
+		release <- struct{}{}
+	}
+	close(release)
+
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 tasks at once; actual %d", peak.Load())
+	}
+}
+
+func TestGroupFailFast(t *testing.T) {
+	g, ctx := New(context.Background(), FailFast())
+
+	errBoom := errors.New("boom")
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Go(func(ctx context.Context) error { return errBoom })
+
+	err := g.Wait()
+	if !errors.Is(err, errBoom) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected both %v and %v; actual %v", errBoom, context.Canceled, err)
+	}
+	if ctx.Err() == nil {
+		t.Error("expected the group ctx to be cancelled")
+	}
+}
+
+func TestGroupGoContext(t *testing.T) {
+	g, _ := New(context.Background(), WithLimit(1))
+
+	release := make(chan struct{})
+	g.Go(func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	//the only slot is taken, waiting for it gives up with ctx.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if err := g.GoContext(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
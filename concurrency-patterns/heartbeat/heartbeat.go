@@ -0,0 +1,85 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+
+	"networking/stablity-patterns/watchdog"
+)
+
+// Run processes every value of in with fn on a new goroutine and pulses on beats after every
+// work unit and every interval while idle, so a supervisor can tell a slow worker from a dead
+// one. Both pulses come from the worker's own loop: if fn stalls, the beats stop too.
+//
+// A pulse is dropped when nobody is listening, a missed beat never holds up the work. Both
+// channels are closed once in is closed or ctx is done.
+func Run[In, Out any](ctx context.Context, interval time.Duration, in <-chan In, fn func(ctx context.Context, v In) Out) (beats <-chan struct{}, out <-chan Out) {
+	beatCh := make(chan struct{}, 1)
+	outCh := make(chan Out)
+
+	go func() {
+		defer close(beatCh)
+		defer close(outCh)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		pulse := func() {
+			select {
+			case beatCh <- struct{}{}:
+			default:
+			}
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+
+				res := fn(ctx, v)
+				pulse()
+
+				//keep pulsing while the consumer makes us wait, we're still alive.
+				for sent := false; !sent; {
+					select {
+					case outCh <- res:
+						sent = true
+					case <-ticker.C:
+						pulse()
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-ticker.C:
+				pulse()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return beatCh, outCh
+}
+
+// Watch returns watchdog.ErrStalled as soon as beats stays silent for timeout, nil once beats is
+// closed, or ctx.Err() once ctx is done.
+func Watch(ctx context.Context, beats <-chan struct{}, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case _, ok := <-beats:
+			if !ok {
+				return nil
+			}
+			timer.Reset(timeout)
+		case <-timer.C:
+			return watchdog.ErrStalled
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
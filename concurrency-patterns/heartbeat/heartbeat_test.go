@@ -0,0 +1,67 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"networking/stablity-patterns/watchdog"
+)
+
+func TestRunIdleBeats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//nothing to do, the interval still proves the worker is alive.
+	beats, _ := Run(ctx, time.Millisecond*5, make(chan int), func(ctx context.Context, v int) int { return v })
+
+	for range 3 {
+		select {
+		case <-beats:
+		case <-time.After(time.Second):
+			t.Fatal("expected an idle beat")
+		}
+	}
+}
+
+func TestRunResults(t *testing.T) {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := range 3 {
+			in <- i
+		}
+	}()
+
+	beats, out := Run(context.Background(), time.Hour, in, func(ctx context.Context, v int) int { return v * 2 })
+
+	sum := 0
+	for range 3 {
+		//one beat per work unit, pulsed before its result is handed over.
+		if _, ok := <-beats; !ok {
+			t.Fatal("expected a beat per work unit")
+		}
+		sum += <-out
+	}
+	if sum != 6 {
+		t.Errorf("expected 6; actual %d", sum)
+	}
+}
+
+func TestWatchStalled(t *testing.T) {
+	in := make(chan int, 1)
+	in <- 1
+	release := make(chan struct{})
+	defer close(release)
+
+	//the worker hangs on its first unit, so the beats stop.
+	beats, _ := Run(context.Background(), time.Millisecond*5, in, func(ctx context.Context, v int) int {
+		<-release
+		return v
+	})
+
+	if err := Watch(context.Background(), beats, time.Millisecond*50); !errors.Is(err, watchdog.ErrStalled) {
+		t.Fatalf("expected %v; actual %v", watchdog.ErrStalled, err)
+	}
+}
@@ -0,0 +1,138 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"runtime"
+
+	"networking/concurrency-patterns/group"
+)
+
+type options struct {
+	workers  int
+	failFast bool
+}
+
+// Option configures ForEach and Map.
+type Option func(*options)
+
+// Workers caps the number of items processed at the same time. Defaults to GOMAXPROCS, n <= 0
+// means no limit: every item gets its own goroutine right away.
+func Workers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// FailFast stops at the first error: no new item is started and the ctx of the running ones is
+// cancelled. By default every item is processed and all the errors are returned.
+func FailFast() Option {
+	return func(o *options) {
+		o.failFast = true
+	}
+}
+
+// newGroup returns the group the items run on, and its ctx.
+func newGroup(ctx context.Context, opts []Option) (*group.Group, context.Context) {
+	o := options{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	groupOpts := []group.Option{group.WithLimit(o.workers)}
+	if o.failFast {
+		groupOpts = append(groupOpts, group.FailFast())
+	}
+	return group.New(ctx, groupOpts...)
+}
+
+// wait waits for the items of g. When the caller gave up before every item was started, ctx.Err()
+// is returned along with the errors of the items, so skipped items never look like a success.
+func wait(ctx context.Context, g *group.Group, skipped bool) error {
+	err := g.Wait()
+	if skipped && ctx.Err() != nil {
+		return errors.Join(err, ctx.Err())
+	}
+	return err
+}
+
+// ForEach calls fn for every item, on at most Workers goroutines at once, and returns the errors
+// joined. A panic in fn is returned as a *recovery.PanicError. If ctx is done before every item
+// was started, ctx.Err() is part of the returned error.
+func ForEach[T any](ctx context.Context, items []T, fn func(ctx context.Context, item T) error, opts ...Option) error {
+	g, groupCtx := newGroup(ctx, opts)
+
+	skipped := false
+	for _, item := range items {
+		//failed fast, or the caller gave up.
+		if groupCtx.Err() != nil {
+			skipped = true
+			break
+		}
+		//same while waiting for a worker.
+		err := g.GoContext(groupCtx, func(ctx context.Context) error {
+			return fn(ctx, item)
+		})
+		if err != nil {
+			skipped = true
+			break
+		}
+	}
+
+	return wait(ctx, g, skipped)
+}
+
+// ForEachChan is ForEach over the values of a channel, until it's closed or ctx is done.
+func ForEachChan[T any](ctx context.Context, items <-chan T, fn func(ctx context.Context, item T) error, opts ...Option) error {
+	g, groupCtx := newGroup(ctx, opts)
+
+	skipped := false
+loop:
+	for {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				break loop
+			}
+			err := g.GoContext(groupCtx, func(ctx context.Context) error {
+				return fn(ctx, item)
+			})
+			if err != nil {
+				skipped = true
+				break loop
+			}
+		case <-groupCtx.Done():
+			skipped = true
+			break loop
+		}
+	}
+
+	return wait(ctx, g, skipped)
+}
+
+// Map is ForEach returning the result of fn for every item, in the order of items. An item whose
+// fn failed, or never ran because of FailFast, leaves the zero value.
+func Map[In, Out any](ctx context.Context, items []In, fn func(ctx context.Context, item In) (Out, error), opts ...Option) ([]Out, error) {
+	results := make([]Out, len(items))
+
+	type indexed struct {
+		i    int
+		item In
+	}
+	idx := make([]indexed, len(items))
+	for i, item := range items {
+		idx[i] = indexed{i, item}
+	}
+
+	//every goroutine writes its own index, so no locking is needed.
+	err := ForEach(ctx, idx, func(ctx context.Context, it indexed) error {
+		res, err := fn(ctx, it.item)
+		if err != nil {
+			return err
+		}
+		results[it.i] = res
+		return nil
+	}, opts...)
+
+	return results, err
+}
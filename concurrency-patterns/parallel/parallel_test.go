@@ -0,0 +1,125 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+
+	var running, peak atomic.Int64
+	res, err := Map(context.Background(), items, func(ctx context.Context, n int) (string, error) {
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); cur > p && !peak.CompareAndSwap(p, cur); p = peak.Load() {
+		}
+
+		time.Sleep(time.Millisecond * 5)
+		return fmt.Sprint(n * n), nil
+	}, Workers(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"1", "4", "9", "16", "25", "36", "49", "64"}
+	if !slices.Equal(res, expected) {
+		t.Errorf("expected %v; actual %v", expected, res)
+	}
+	if peak.Load() > 3 {
+		t.Errorf("expected at most 3 workers; actual %d", peak.Load())
+	}
+}
+
+func TestForEachErrors(t *testing.T) {
+	errOdd := errors.New("odd")
+	items := []int{1, 2, 3, 4, 5}
+
+	var calls atomic.Int64
+	fn := func(ctx context.Context, n int) error {
+		calls.Add(1)
+		if n%2 == 1 {
+			return fmt.Errorf("%d: %w", n, errOdd)
+		}
+		return nil
+	}
+
+	err := ForEach(context.Background(), items, fn, Workers(1))
+	if !errors.Is(err, errOdd) {
+		t.Fatalf("expected %v; actual %v", errOdd, err)
+	}
+	//collect all, every item ran and every failure is there.
+	if calls.Load() != 5 || len(err.(interface{ Unwrap() []error }).Unwrap()) != 3 {
+		t.Errorf("expected 5 calls and 3 errors; actual %d and %v", calls.Load(), err)
+	}
+
+	calls.Store(0)
+	if err := ForEach(context.Background(), items, fn, Workers(1), FailFast()); !errors.Is(err, errOdd) {
+		t.Fatalf("expected %v; actual %v", errOdd, err)
+	}
+	if calls.Load() >= 5 {
+		t.Errorf("expected fail fast to skip items; actual %d calls", calls.Load())
+	}
+}
+
+func TestForEachChan(t *testing.T) {
+	items := make(chan int)
+	go func() {
+		defer close(items)
+		for i := range 10 {
+			items <- i
+		}
+	}()
+
+	var sum atomic.Int64
+	err := ForEachChan(context.Background(), items, func(ctx context.Context, n int) error {
+		sum.Add(int64(n))
+		return nil
+	}, Workers(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Load() != 45 {
+		t.Errorf("expected 45; actual %d", sum.Load())
+	}
+}
+
+func TestForEachCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//the only worker is stuck until the caller gives up, the other items never start.
+	var started atomic.Int32
+	err := ForEach(ctx, []int{1, 2, 3}, func(ctx context.Context, n int) error {
+		started.Add(1)
+		cancel()
+		<-ctx.Done()
+		return nil
+	}, Workers(1))
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v; actual %v", context.Canceled, err)
+	}
+	if started.Load() != 1 {
+		t.Errorf("expected a single item to start; actual %d", started.Load())
+	}
+}
+
+func TestForEachChanCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	//never closed, the caller's ctx is the only way out.
+	items := make(chan int)
+	err := ForEachChan(ctx, items, func(ctx context.Context, n int) error {
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func ExampleThen() {
+	words := make(chan string)
+	go func() {
+		defer close(words)
+		for _, w := range []string{"fan", "out", "fan", "in"} {
+			words <- w
+		}
+	}()
+
+	upper := New(func(ctx context.Context, w string) (string, error) {
+		return strings.ToUpper(w), nil
+	})
+	length := Then(upper, func(ctx context.Context, w string) (int, error) {
+		return len(w), nil
+	})
+
+	out, wait := length.Run(context.Background(), words)
+	for n := range out {
+		fmt.Println(n)
+	}
+	if err := wait(); err != nil {
+		fmt.Println("Err:", err)
+	}
+
+	// Output:
+	// 3
+	// 3
+	// 3
+	// 2
+}
@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// Stage turns one item into the next stage's input. A Stage returning an error stops the whole
+// pipeline.
+type Stage[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+type options struct {
+	workers int
+	buffer  int
+}
+
+// Option configures a single stage.
+type Option func(*options)
+
+// Workers runs n copies of the stage, fanning its input out to them and their output back in.
+// Items of a stage with more than one worker can come out in a different order. Defaults to 1.
+func Workers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// Buffer sets the size of the stage's output channel. Defaults to 0.
+func Buffer(n int) Option {
+	return func(o *options) {
+		o.buffer = n
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{workers: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// connector starts the goroutines of one or more stages reading from in, every goroutine is
+// tracked by wg and reports its error with fail.
+type connector[In, Out any] func(ctx context.Context, fail context.CancelCauseFunc, wg *sync.WaitGroup, in <-chan In) <-chan Out
+
+/*
+Pipeline is a chain of stages connected by channels, the generic form of the fan-out and fan-in
+examples: every stage runs on its own goroutines, Workers fans a stage out and merges its output
+back into a single channel for the next one.
+
+The first error returned by any stage cancels the ctx every stage runs with, so upstream stages stop
+producing and downstream ones stop consuming, and Run's wait returns that error.
+
+Go methods can't introduce type parameters, so the method Then only appends a stage of the same
+type, the function Then appends one that changes it.
+*/
+type Pipeline[In, Out any] struct {
+	connect connector[In, Out]
+}
+
+// New creates a Pipeline made of a single stage.
+func New[In, Out any](stage Stage[In, Out], opts ...Option) *Pipeline[In, Out] {
+	return &Pipeline[In, Out]{connect: start(stage, newOptions(opts))}
+}
+
+// Then appends a stage whose output has a different type than p's.
+func Then[In, Mid, Out any](p *Pipeline[In, Mid], stage Stage[Mid, Out], opts ...Option) *Pipeline[In, Out] {
+	next := start(stage, newOptions(opts))
+	return &Pipeline[In, Out]{
+		connect: func(ctx context.Context, fail context.CancelCauseFunc, wg *sync.WaitGroup, in <-chan In) <-chan Out {
+			return next(ctx, fail, wg, p.connect(ctx, fail, wg, in))
+		},
+	}
+}
+
+// Then appends a stage with the same input and output type.
+func (p *Pipeline[In, Out]) Then(stage Stage[Out, Out], opts ...Option) *Pipeline[In, Out] {
+	return Then(p, stage, opts...)
+}
+
+// Run feeds source through the stages and returns the output of the last one. The output is
+// closed once source is drained, a stage failed, or ctx is done. wait blocks until every stage
+// has stopped and returns the first stage error or the ctx error, so the output has to be read
+// until it is closed, or ctx cancelled, before calling it.
+func (p *Pipeline[In, Out]) Run(ctx context.Context, source <-chan In) (out <-chan Out, wait func() error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	var wg sync.WaitGroup
+
+	out = p.connect(ctx, cancel, &wg, source)
+
+	wait = sync.OnceValue(func() error {
+		wg.Wait()
+		var err error
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		cancel(nil)
+		return err
+	})
+
+	return out, wait
+}
+
+// All is Run as an iterator, it yields every output and, if the pipeline failed, a last zero
+// value with the error. Breaking out of the loop cancels the pipeline and waits for its stages.
+func (p *Pipeline[In, Out]) All(ctx context.Context, source <-chan In) iter.Seq2[Out, error] {
+	return func(yield func(Out, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		out, wait := p.Run(ctx, source)
+		for v := range out {
+			if !yield(v, nil) {
+				cancel()
+				//the stages stop on the cancel, let them drain into the void.
+				for range out {
+				}
+				_ = wait()
+				return
+			}
+		}
+
+		if err := wait(); err != nil {
+			var zero Out
+			yield(zero, err)
+		}
+	}
+}
+
+// start returns the connector running stage with o.
+func start[In, Out any](stage Stage[In, Out], o options) connector[In, Out] {
+	return func(ctx context.Context, fail context.CancelCauseFunc, wg *sync.WaitGroup, in <-chan In) <-chan Out {
+		out := make(chan Out, o.buffer)
+
+		//the workers of this stage only, out is closed once all of them are done.
+		var stageWG sync.WaitGroup
+		stageWG.Add(o.workers)
+		wg.Add(o.workers)
+
+		for range o.workers {
+			go func() {
+				defer wg.Done()
+				defer stageWG.Done()
+
+				for {
+					select {
+					case v, ok := <-in:
+						if !ok {
+							return
+						}
+
+						res, err := stage(ctx, v)
+						if err != nil {
+							fail(err)
+							return
+						}
+
+						select {
+						case out <- res:
+						case <-ctx.Done():
+							return
+						}
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		go func() {
+			stageWG.Wait()
+			close(out)
+		}()
+
+		return out
+	}
+}
@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func source(n int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := range n {
+			ch <- i
+		}
+	}()
+	return ch
+}
+
+func TestPipeline(t *testing.T) {
+	square := func(ctx context.Context, n int) (int, error) { return n * n, nil }
+	format := func(ctx context.Context, n int) (string, error) { return strconv.Itoa(n), nil }
+
+	p := Then(New(square, Workers(4)).Then(func(ctx context.Context, n int) (int, error) {
+		return n + 1, nil
+	}), format, Buffer(2))
+
+	out, wait := p.Run(context.Background(), source(10))
+
+	var actual []string
+	for s := range out {
+		actual = append(actual, s)
+	}
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	//square ran with 4 workers, so the order is not guaranteed.
+	slices.SortFunc(actual, func(a, b string) int {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x - y
+	})
+	expected := []string{"1", "2", "5", "10", "17", "26", "37", "50", "65", "82"}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected %v; actual %v", expected, actual)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	//an endless source, only the error can stop the pipeline.
+	src := make(chan int)
+	srcCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		defer close(src)
+		for i := 0; ; i++ {
+			select {
+			case src <- i:
+			case <-srcCtx.Done():
+				return
+			}
+		}
+	}()
+
+	p := New(func(ctx context.Context, n int) (int, error) {
+		if n == 5 {
+			return 0, errBoom
+		}
+		return n, nil
+	}, Workers(2)).Then(func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	})
+
+	out, wait := p.Run(context.Background(), src)
+	for range out {
+	}
+
+	if err := wait(); !errors.Is(err, errBoom) {
+		t.Fatalf("expected %v; actual %v", errBoom, err)
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	out, wait := New(func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	}).Run(ctx, make(chan int))
+
+	cancel()
+	for range out {
+	}
+
+	if err := wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v; actual %v", context.Canceled, err)
+	}
+}
+
+func TestPipelineAll(t *testing.T) {
+	errBoom := errors.New("boom")
+	p := New(func(ctx context.Context, n int) (int, error) {
+		if n == 3 {
+			return 0, errBoom
+		}
+		return n, nil
+	})
+
+	var vals []int
+	var err error
+	for v, e := range p.All(context.Background(), source(10)) {
+		if e != nil {
+			err = e
+			break
+		}
+		vals = append(vals, v)
+	}
+
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected %v; actual %v", errBoom, err)
+	}
+	if !slices.Equal(vals, []int{0, 1, 2}) {
+		t.Errorf("expected [0 1 2]; actual %v", vals)
+	}
+
+	//breaking early must not leave the stages running or block.
+	for v := range p.All(context.Background(), source(10)) {
+		if v == 1 {
+			break
+		}
+	}
+}
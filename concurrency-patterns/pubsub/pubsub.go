@@ -0,0 +1,230 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by Publish after the broker has been closed.
+var ErrClosed = errors.New("broker closed")
+
+// Policy decides what Publish does when a subscriber's buffer is full.
+type Policy int
+
+const (
+	// Block waits for the subscriber to make room, or for the publisher's ctx.
+	Block Policy = iota
+	// Drop skips the message for that subscriber only.
+	Drop
+	// Disconnect unsubscribes the subscriber and closes its channel.
+	Disconnect
+)
+
+type options struct {
+	buffer int
+	policy Policy
+}
+
+// Option configures a subscription.
+type Option func(*options)
+
+// WithBuffer sets the size of the subscription's channel. Defaults to 0.
+func WithBuffer(n int) Option {
+	return func(o *options) {
+		o.buffer = n
+	}
+}
+
+// WithPolicy sets what happens when the subscriber falls behind. Defaults to Block.
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// Subscription receives the messages of a single topic on C until it's unsubscribed.
+type Subscription[T any] struct {
+	topic  string
+	policy Policy
+	broker *Broker[T]
+	ch     chan T
+
+	//closed on unsubscribe, unblocks publishers waiting on this subscriber.
+	done chan struct{}
+	once sync.Once
+	//held for reading while sending, so ch is never closed under a publisher.
+	mu      sync.RWMutex
+	dropped atomic.Int64
+}
+
+// C returns the channel the messages are delivered on, closed once the subscription ends.
+func (s *Subscription[T]) C() <-chan T {
+	return s.ch
+}
+
+// Dropped returns the number of messages skipped by the Drop policy.
+func (s *Subscription[T]) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe ends the subscription and closes C, it's safe to call more than once.
+func (s *Subscription[T]) Unsubscribe() {
+	s.once.Do(func() {
+		s.broker.remove(s)
+		close(s.done)
+
+		s.mu.Lock()
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+// send delivers msg according to the policy and reports whether the subscriber has to be disconnected.
+func (s *Subscription[T]) send(ctx context.Context, msg T) (disconnect bool, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	select {
+	case <-s.done:
+		return false, nil
+	default:
+	}
+
+	switch s.policy {
+	case Drop, Disconnect:
+		select {
+		case s.ch <- msg:
+			return false, nil
+		default:
+		}
+
+		if s.policy == Drop {
+			s.dropped.Add(1)
+			return false, nil
+		}
+		return true, nil
+
+	default:
+		select {
+		case s.ch <- msg:
+			return false, nil
+		case <-s.done:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+/*
+Broker is an in-memory publish/subscribe hub: messages published to a topic are delivered to every
+subscriber of that topic, each on its own buffered channel.
+
+A subscriber that falls behind is handled by its own Policy, so one slow subscriber only holds up
+publishers when it asked for Block.
+*/
+type Broker[T any] struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// NewBroker creates an empty Broker.
+func NewBroker[T any]() *Broker[T] {
+	return &Broker[T]{topics: make(map[string]map[*Subscription[T]]struct{})}
+}
+
+// Subscribe subscribes to topic until ctx is done or Unsubscribe is called. Subscribing to a
+// closed broker returns a subscription that is already closed.
+func (b *Broker[T]) Subscribe(ctx context.Context, topic string, opts ...Option) *Subscription[T] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Subscription[T]{
+		topic:  topic,
+		policy: o.policy,
+		broker: b,
+		ch:     make(chan T, o.buffer),
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		s.Unsubscribe()
+		return s
+	}
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[*Subscription[T]]struct{})
+	}
+	b.topics[topic][s] = struct{}{}
+	b.mu.Unlock()
+
+	//exits with the subscription as well, so it doesn't outlive it on a long lived ctx.
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Unsubscribe()
+		case <-s.done:
+		}
+	}()
+	return s
+}
+
+// Publish delivers msg to every current subscriber of topic. It returns ctx.Err() when a
+// blocking subscriber couldn't be served before ctx was done.
+func (b *Broker[T]) Publish(ctx context.Context, topic string, msg T) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	//a snapshot, so no lock is held while waiting on a subscriber.
+	subs := make([]*Subscription[T], 0, len(b.topics[topic]))
+	for s := range b.topics[topic] {
+		subs = append(subs, s)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		disconnect, err := s.send(ctx, msg)
+		if err != nil {
+			return err
+		}
+		if disconnect {
+			s.Unsubscribe()
+		}
+	}
+	return nil
+}
+
+// Close unsubscribes everybody, Publish fails with ErrClosed afterwards.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	var subs []*Subscription[T]
+	for _, topic := range b.topics {
+		for s := range topic {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Unsubscribe()
+	}
+}
+
+func (b *Broker[T]) remove(s *Subscription[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.topics[s.topic], s)
+	if len(b.topics[s.topic]) == 0 {
+		delete(b.topics, s.topic)
+	}
+}
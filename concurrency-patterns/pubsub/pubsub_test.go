@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func collect[T any](ch <-chan T) []T {
+	var out []T
+	for v := range ch {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestBroker(t *testing.T) {
+	b := NewBroker[string]()
+	ctx := context.Background()
+
+	news := b.Subscribe(ctx, "news", WithBuffer(4))
+	sports := b.Subscribe(ctx, "sports", WithBuffer(4))
+
+	_ = b.Publish(ctx, "news", "a")
+	_ = b.Publish(ctx, "sports", "b")
+	_ = b.Publish(ctx, "news", "c")
+	//nobody listens, nothing happens.
+	_ = b.Publish(ctx, "weather", "d")
+
+	b.Close()
+
+	if got := collect(news.C()); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("expected [a c]; actual %v", got)
+	}
+	if got := collect(sports.C()); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("expected [b]; actual %v", got)
+	}
+	if err := b.Publish(ctx, "news", "e"); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v; actual %v", ErrClosed, err)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	b := NewBroker[int]()
+	ctx := context.Background()
+
+	drop := b.Subscribe(ctx, "t", WithBuffer(1), WithPolicy(Drop))
+	disconnect := b.Subscribe(ctx, "t", WithBuffer(1), WithPolicy(Disconnect))
+
+	for i := range 3 {
+		if err := b.Publish(ctx, "t", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if drop.Dropped() != 2 {
+		t.Errorf("expected 2 dropped; actual %d", drop.Dropped())
+	}
+
+	//the slow subscriber got its first message, then was cut off.
+	if got := collect(disconnect.C()); !slices.Equal(got, []int{0}) {
+		t.Errorf("expected [0]; actual %v", got)
+	}
+
+	drop.Unsubscribe()
+	if got := collect(drop.C()); !slices.Equal(got, []int{0}) {
+		t.Errorf("expected [0]; actual %v", got)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	b := NewBroker[int]()
+	s := b.Subscribe(context.Background(), "t")
+
+	//nobody reads, so the publisher gives up with its ctx.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if err := b.Publish(ctx, "t", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+
+	//a blocked publisher is released when the subscriber leaves.
+	done := make(chan error, 1)
+	go func() { done <- b.Publish(context.Background(), "t", 2) }()
+	time.Sleep(time.Millisecond * 10)
+	s.Unsubscribe()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubscribeCtx(t *testing.T) {
+	b := NewBroker[int]()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := b.Subscribe(ctx, "t")
+	cancel()
+
+	//closed once ctx is done.
+	for range s.C() {
+	}
+}
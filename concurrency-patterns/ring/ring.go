@@ -0,0 +1,147 @@
+package ring
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Policy decides which value is lost when Push finds the buffer full.
+type Policy int
+
+const (
+	// DropOldest overwrites the oldest buffered value, the consumer always sees the latest data.
+	DropOldest Policy = iota
+	// DropNewest discards the value being pushed, the consumer sees the data in arrival order without gaps at the front.
+	DropNewest
+)
+
+/*
+Buffer is a bounded ring buffer between a producer and a consumer that never blocks the producer:
+once it's full the Policy decides what is lost, and Dropped counts it. It's meant for producers
+that can't wait, like a datagram read loop, where a blocked read means the kernel silently drops
+packets instead.
+
+The consumer reads from C. One value can sit in the forwarding goroutine on top of the buffered ones.
+*/
+type Buffer[T any] struct {
+	policy Policy
+
+	mu     sync.Mutex
+	items  []T
+	head   int
+	n      int
+	closed bool
+
+	//signals the forwarder that there is something to pop, or that the buffer is closed.
+	notify  chan struct{}
+	out     chan T
+	dropped atomic.Int64
+}
+
+// New creates a Buffer of size values and starts forwarding them on C.
+func New[T any](size int, policy Policy) *Buffer[T] {
+	b := &Buffer[T]{
+		policy: policy,
+		items:  make([]T, max(size, 1)),
+		notify: make(chan struct{}, 1),
+		out:    make(chan T),
+	}
+
+	go b.forward()
+	return b
+}
+
+// C returns the channel the buffered values are delivered on, closed after Close once the buffer is empty.
+func (b *Buffer[T]) C() <-chan T {
+	return b.out
+}
+
+// Dropped returns the number of values lost to the policy.
+func (b *Buffer[T]) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+// Len returns the number of buffered values.
+func (b *Buffer[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.n
+}
+
+// Push adds v without blocking and reports whether v was buffered. After Close it's a no-op.
+func (b *Buffer[T]) Push(v T) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return false
+	}
+
+	if b.n == len(b.items) {
+		b.dropped.Add(1)
+		if b.policy == DropNewest {
+			return false
+		}
+		//overwrite the oldest one.
+		b.items[b.head] = v
+		b.head = (b.head + 1) % len(b.items)
+		b.signal()
+		return true
+	}
+
+	b.items[(b.head+b.n)%len(b.items)] = v
+	b.n++
+	b.signal()
+	return true
+}
+
+// Close stops accepting values, the ones already buffered are still delivered before C is closed.
+func (b *Buffer[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	b.signal()
+}
+
+// signal wakes up the forwarder, it must be called with mu held.
+func (b *Buffer[T]) signal() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop takes the oldest value, ok is false when the buffer is empty.
+func (b *Buffer[T]) pop() (v T, ok bool, closed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.n == 0 {
+		return v, false, b.closed
+	}
+
+	var zero T
+	v = b.items[b.head]
+	b.items[b.head] = zero
+	b.head = (b.head + 1) % len(b.items)
+	b.n--
+	return v, true, b.closed
+}
+
+func (b *Buffer[T]) forward() {
+	defer close(b.out)
+
+	for {
+		v, ok, closed := b.pop()
+		if !ok {
+			if closed {
+				return
+			}
+			<-b.notify
+			continue
+		}
+
+		b.out <- v
+	}
+}
@@ -0,0 +1,65 @@
+package ring
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func collect[T any](ch <-chan T) []T {
+	var out []T
+	for v := range ch {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestBuffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		expected []int
+	}{
+		{name: "drop oldest", policy: DropOldest, expected: []int{7, 8, 9}},
+		{name: "drop newest", policy: DropNewest, expected: []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New[int](3, tt.policy)
+
+			//the forwarder holds the first value it pops, wait for it so the rest of the test is deterministic.
+			b.Push(-1)
+			waitLen(t, b, 0)
+
+			for i := range 10 {
+				b.Push(i)
+			}
+			b.Close()
+
+			//-1 is the one the forwarder was holding.
+			got := collect(b.C())
+			if !slices.Equal(got, append([]int{-1}, tt.expected...)) {
+				t.Errorf("expected %v; actual %v", append([]int{-1}, tt.expected...), got)
+			}
+			if b.Dropped() != 7 {
+				t.Errorf("expected 7 dropped; actual %d", b.Dropped())
+			}
+			if b.Push(10) {
+				t.Error("expected Push to fail after Close")
+			}
+		})
+	}
+}
+
+func waitLen[T any](t *testing.T, b *Buffer[T], n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for b.Len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d buffered; actual %d", n, b.Len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,121 @@
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+/*
+Semaphore is a weighted semaphore: every holder takes n units out of a fixed size, so a heavy
+operation can count for more than a light one, e.g. a large transfer against a connection limit.
+
+Waiters are served strictly first in, first out. A waiter asking for more units than are free
+blocks everyone behind it, even smaller requests that would fit, so large requests can't starve.
+*/
+type Semaphore struct {
+	size int64
+
+	mu      sync.Mutex
+	cur     int64
+	waiters list.List
+}
+
+// New creates a Semaphore with size units.
+func New(size int64) *Semaphore {
+	return &Semaphore{size: size}
+}
+
+// Acquire takes n units, waiting for them until ctx is done. A request larger than the
+// semaphore's size waits for ctx, as it can never be served.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	//only jump in when nobody is queued already, that's what keeps it fair.
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	if n > s.size {
+		s.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	w := waiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		select {
+		case <-w.ready:
+			//got the units right as ctx was done, keep them, the caller sees a success.
+			return nil
+		default:
+		}
+
+		isFront := s.waiters.Front() == elem
+		s.waiters.Remove(elem)
+		//the ones behind us might fit now that we're gone.
+		if isFront && s.size > s.cur {
+			s.notify()
+		}
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes n units only if they're free right away, and reports whether it did.
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release gives back n units, it panics when more units are released than held.
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("semaphore: released more than held")
+	}
+	s.notify()
+}
+
+// notify hands the free units to the waiters in order, it must be called with mu held.
+func (s *Semaphore) notify() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+
+		w := front.Value.(waiter)
+		if s.size-s.cur < w.n {
+			//not enough for the first one, the others have to wait behind it.
+			return
+		}
+
+		s.cur += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
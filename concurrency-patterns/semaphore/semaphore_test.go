@@ -0,0 +1,104 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSemaphore(t *testing.T) {
+	s := New(5)
+	ctx := context.Background()
+
+	if err := s.Acquire(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if !s.TryAcquire(2) {
+		t.Fatal("expected the remaining 2 units to be free")
+	}
+	if s.TryAcquire(1) {
+		t.Fatal("expected the semaphore to be full")
+	}
+
+	s.Release(3)
+	if !s.TryAcquire(3) {
+		t.Fatal("expected the released units to be free")
+	}
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	s := New(4)
+	ctx := context.Background()
+	_ = s.Acquire(ctx, 3)
+
+	//a big request queues first, the small one behind it must not overtake it.
+	big := make(chan struct{})
+	go func() {
+		_ = s.Acquire(ctx, 4)
+		close(big)
+	}()
+	waitForWaiters(t, s, 1)
+
+	small := make(chan struct{})
+	go func() {
+		_ = s.Acquire(ctx, 1)
+		close(small)
+	}()
+	waitForWaiters(t, s, 2)
+
+	if s.TryAcquire(1) {
+		t.Fatal("expected TryAcquire to respect the queue")
+	}
+
+	s.Release(3)
+	<-big
+	select {
+	case <-small:
+		t.Fatal("expected the small request to wait for the big one to release")
+	default:
+	}
+
+	s.Release(4)
+	<-small
+}
+
+func TestSemaphoreCancel(t *testing.T) {
+	s := New(2)
+	_ = s.Acquire(context.Background(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	//the cancelled waiter was blocking the small one, it's served as soon as it leaves.
+	small := make(chan struct{})
+	go func() {
+		waitForWaiters(t, s, 1)
+		_ = s.Acquire(context.Background(), 1)
+		close(small)
+	}()
+
+	if err := s.Acquire(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+	<-small
+}
+
+func waitForWaiters(t *testing.T, s *Semaphore, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		l := s.waiters.Len()
+		s.mu.Unlock()
+		if l >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("expected %d waiters; actual %d", n, l)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
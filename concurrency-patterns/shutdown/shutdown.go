@@ -0,0 +1,131 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrHookTimeout is reported for every hook still running when the shutdown deadline passed.
+var ErrHookTimeout = errors.New("shutdown hook timed out")
+
+// Hook is a single shutdown step, it should return once ctx is done even if it isn't finished.
+type Hook func(ctx context.Context) error
+
+type hook struct {
+	name string
+	fn   Hook
+}
+
+// Result is the outcome of a single hook.
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+	//the deadline passed before the hook returned, or before it even started.
+	TimedOut bool
+}
+
+/*
+Coordinator runs the shutdown of a server as an ordered list of hooks, e.g. stop accepting, drain
+conns, flush buffers, under a single deadline for all of them.
+
+The hooks run one after the other in registration order. A hook that's still running at the
+deadline is abandoned, and the hooks after it are skipped. Both are reported with ErrHookTimeout,
+so it's clear which step hung.
+*/
+type Coordinator struct {
+	timeout time.Duration
+
+	mu    sync.Mutex
+	hooks []hook
+	once  sync.Once
+	res   []Result
+	err   error
+}
+
+// New creates a Coordinator giving the whole shutdown timeout to complete.
+func New(timeout time.Duration) *Coordinator {
+	return &Coordinator{timeout: timeout}
+}
+
+// Register appends a hook, hooks run in the order they were registered.
+func (c *Coordinator) Register(name string, fn Hook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hook{name: name, fn: fn})
+}
+
+// Wait blocks until one of signals arrives, SIGINT and SIGTERM by default, or ctx is done,
+// and then runs Shutdown.
+func (c *Coordinator) Wait(ctx context.Context, signals ...os.Signal) ([]Result, error) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, signals...)
+	defer stop()
+	<-ctx.Done()
+
+	//the shutdown gets its own deadline, the ctx that triggered it is already done.
+	return c.Shutdown(context.WithoutCancel(ctx))
+}
+
+// Shutdown runs the hooks under the coordinator's timeout, or ctx's deadline if it comes first.
+// It returns the result of every hook and their errors joined. It only runs once, later calls
+// return the same results.
+func (c *Coordinator) Shutdown(ctx context.Context) ([]Result, error) {
+	c.once.Do(func() {
+		c.mu.Lock()
+		hooks := append([]hook(nil), c.hooks...)
+		c.mu.Unlock()
+
+		c.res, c.err = run(ctx, c.timeout, hooks)
+	})
+
+	return c.res, c.err
+}
+
+func run(ctx context.Context, timeout time.Duration, hooks []hook) ([]Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make([]Result, 0, len(hooks))
+	var errs []error
+
+	for _, h := range hooks {
+		r := Result{Name: h.name}
+
+		if ctx.Err() != nil {
+			r.TimedOut = true
+			r.Err = ErrHookTimeout
+		} else {
+			start := time.Now()
+			errCh := make(chan error, 1)
+			//on its own goroutine, so a hook ignoring ctx can't hold up the deadline.
+			go func() {
+				errCh <- h.fn(ctx)
+			}()
+
+			select {
+			case r.Err = <-errCh:
+			case <-ctx.Done():
+				r.TimedOut = true
+				r.Err = ErrHookTimeout
+			}
+			r.Duration = time.Since(start)
+		}
+
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, r.Err))
+		}
+		results = append(results, r)
+	}
+
+	return results, errors.Join(errs...)
+}
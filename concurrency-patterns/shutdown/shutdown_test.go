@@ -0,0 +1,96 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	c := New(time.Millisecond * 50)
+
+	var order []string
+	errFlush := errors.New("flush")
+	c.Register("stop accepting", func(ctx context.Context) error {
+		order = append(order, "stop accepting")
+		return nil
+	})
+	c.Register("flush buffers", func(ctx context.Context) error {
+		order = append(order, "flush buffers")
+		return errFlush
+	})
+	c.Register("drain conns", func(ctx context.Context) error {
+		//ignores ctx, the coordinator must not wait for it.
+		time.Sleep(time.Second)
+		return nil
+	})
+	c.Register("close logs", func(ctx context.Context) error {
+		order = append(order, "close logs")
+		return nil
+	})
+
+	start := time.Now()
+	results, err := c.Shutdown(context.Background())
+	if time.Since(start) > time.Millisecond*500 {
+		t.Errorf("expected the deadline to cut the shutdown short; took %v", time.Since(start))
+	}
+
+	if !errors.Is(err, errFlush) || !errors.Is(err, ErrHookTimeout) {
+		t.Errorf("expected both %v and %v; actual %v", errFlush, ErrHookTimeout, err)
+	}
+	if !slices.Equal(order, []string{"stop accepting", "flush buffers"}) {
+		t.Errorf("expected the hooks in order up to the hung one; actual %v", order)
+	}
+
+	timedOut := make([]bool, 0, len(results))
+	for _, r := range results {
+		timedOut = append(timedOut, r.TimedOut)
+	}
+	if !slices.Equal(timedOut, []bool{false, false, true, true}) {
+		t.Errorf("expected the last two hooks to time out; actual %v", timedOut)
+	}
+
+	//only runs once.
+	again, _ := c.Shutdown(context.Background())
+	if len(again) != 4 || len(order) != 2 {
+		t.Error("expected a second Shutdown to return the first results")
+	}
+}
+
+func TestWaitSignal(t *testing.T) {
+	c := New(time.Second)
+	ran := false
+	c.Register("hook", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	//catch it in the test too, so a Wait that isn't listening yet can't let the signal kill the process.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGUSR1)
+	defer signal.Stop(guard)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Wait(context.Background(), syscall.SIGUSR1)
+		done <- err
+	}()
+
+	//give Wait the time to install its handler.
+	time.Sleep(time.Millisecond * 50)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !ran {
+		t.Error("expected the hook to run on the signal")
+	}
+}
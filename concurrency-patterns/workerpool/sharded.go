@@ -0,0 +1,93 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+)
+
+/*
+Sharded is a pool whose tasks carry a key, every task with the same key runs on the same shard,
+one after the other in submission order, while different keys run in parallel. It suits
+connection scoped work, where the writes of one connection must not be reordered but a slow
+connection shouldn't hold the others back.
+
+Each shard is a single worker Pool with its own queue, so the overflow policy applies per shard.
+*/
+type Sharded struct {
+	shards []*Pool
+}
+
+// NewSharded creates a Sharded pool of shards shards, each queueing up to queueSize tasks.
+// WithMaxWorkers is ignored, a shard growing a second worker would break the ordering.
+func NewSharded(shards int, queueSize int, policy Policy, opts ...Option) *Sharded {
+	shards = max(shards, 1)
+	//appended last so it wins over the caller's.
+	opts = append(opts[:len(opts):len(opts)], WithMaxWorkers(1, 0))
+
+	s := &Sharded{shards: make([]*Pool, shards)}
+	for i := range s.shards {
+		s.shards[i] = New(1, queueSize, policy, opts...)
+	}
+
+	return s
+}
+
+// Submit hands task to the shard owning key.
+func (s *Sharded) Submit(ctx context.Context, key string, task Task) error {
+	return s.shards[s.shard(key)].Submit(ctx, task)
+}
+
+func (s *Sharded) shard(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(s.shards)))
+}
+
+// Pending returns the number of tasks waiting across all shards.
+func (s *Sharded) Pending() int {
+	n := 0
+	for _, p := range s.shards {
+		n += p.Pending()
+	}
+	return n
+}
+
+// Dropped returns the number of tasks discarded across all shards.
+func (s *Sharded) Dropped() int64 {
+	var n int64
+	for _, p := range s.shards {
+		n += p.Dropped()
+	}
+	return n
+}
+
+// Panics returns the number of tasks that panicked across all shards.
+func (s *Sharded) Panics() int64 {
+	var n int64
+	for _, p := range s.shards {
+		n += p.Panics()
+	}
+	return n
+}
+
+// Shutdown shuts every shard down concurrently under the same ctx, see Pool.Shutdown.
+func (s *Sharded) Shutdown(ctx context.Context) error {
+	errs := make(chan error, len(s.shards))
+	for _, p := range s.shards {
+		go func() {
+			errs <- p.Shutdown(ctx)
+		}()
+	}
+
+	var err error
+	for range s.shards {
+		err = errors.Join(err, <-errs)
+	}
+
+	//every shard returns the same ctx.Err(), report it once.
+	if err != nil {
+		return ctx.Err()
+	}
+	return nil
+}
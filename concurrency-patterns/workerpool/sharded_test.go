@@ -0,0 +1,65 @@
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedOrdering(t *testing.T) {
+	s := NewSharded(4, 100, Block)
+
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	for i := range 50 {
+		for _, key := range []string{"a", "b", "c"} {
+			err := s.Submit(context.Background(), key, func(ctx context.Context) {
+				mu.Lock()
+				defer mu.Unlock()
+				seen[key] = append(seen[key], i)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, order := range seen {
+		if !slices.IsSorted(order) || len(order) != 50 {
+			t.Errorf("%s: expected 50 tasks in submission order; actual %v", key, order)
+		}
+	}
+}
+
+func TestShardedParallel(t *testing.T) {
+	s := NewSharded(8, 10, Block)
+	defer func() { _ = s.Shutdown(context.Background()) }()
+
+	//find two keys that live on different shards.
+	blocked, free := "conn-0", ""
+	for i := 1; free == ""; i++ {
+		if key := fmt.Sprintf("conn-%d", i); s.shard(key) != s.shard(blocked) {
+			free = key
+		}
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	_ = s.Submit(context.Background(), blocked, func(ctx context.Context) { <-release })
+
+	done := make(chan struct{})
+	_ = s.Submit(context.Background(), free, func(ctx context.Context) { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a blocked key not to hold back the other shards")
+	}
+}
@@ -0,0 +1,294 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by Submit with the Reject policy when the queue is full.
+	ErrQueueFull = errors.New("queue full")
+	// ErrClosed is returned by Submit after Shutdown has been called.
+	ErrClosed = errors.New("pool closed")
+)
+
+// Policy decides what Submit does when the queue is full.
+type Policy int
+
+const (
+	// Block waits for space in the queue or for the submitter's ctx.
+	Block Policy = iota
+	// DropOldest discards the oldest queued task to make room for the new one.
+	DropOldest
+	// Reject returns ErrQueueFull right away.
+	Reject
+)
+
+// Task is a unit of work run by the pool, ctx is cancelled when Shutdown gives up on it.
+type Task func(ctx context.Context)
+
+type options struct {
+	maxWorkers int
+	idle       time.Duration
+	onPanic    func(v any, stack []byte)
+}
+
+// Option configures a Pool.
+type Option func(*options)
+
+// WithMaxWorkers lets the pool grow up to max workers while tasks are waiting in the queue,
+// the extra workers exit after idle without a task. By default the worker count is fixed.
+func WithMaxWorkers(max int, idle time.Duration) Option {
+	return func(o *options) {
+		o.maxWorkers = max
+		o.idle = idle
+	}
+}
+
+// OnPanic registers a callback invoked with the value and stack of every task that panics.
+// The worker survives the panic either way.
+func OnPanic(fn func(v any, stack []byte)) Option {
+	return func(o *options) {
+		o.onPanic = fn
+	}
+}
+
+/*
+Pool runs tasks on a set of long lived workers fed by a bounded queue, instead of a goroutine per
+task. When the queue is full the Policy decides between waiting, dropping the oldest task and
+rejecting the new one. With WithMaxWorkers the pool grows while tasks are waiting and shrinks back
+once the extra workers go idle.
+
+A panicking task is recovered and counted, it never takes its worker or the process down.
+*/
+type Pool struct {
+	policy Policy
+	opts   options
+	queue  chan Task
+
+	//protects closed, and makes sure nobody sends on queue once it's closed.
+	mu     sync.RWMutex
+	closed bool
+	//closed by Shutdown before it takes mu, wakes the submitters blocked on a full queue.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	//ctx handed to tasks, cancelled when Shutdown gives up on draining.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wg      sync.WaitGroup
+	workers atomic.Int64
+	dropped atomic.Int64
+	panics  atomic.Int64
+}
+
+// New creates a Pool with workers permanent workers and a queue of queueSize tasks. DropOldest
+// and Reject need room for at least one task, their queue is never smaller.
+func New(workers int, queueSize int, policy Policy, opts ...Option) *Pool {
+	o := options{maxWorkers: workers}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	//at least one worker has to outlive the idle ones, or a queued task could be left behind.
+	workers = max(workers, 1)
+	o.maxWorkers = max(o.maxWorkers, workers)
+	//without a slot DropOldest has nothing to drop and spins, and Reject turns everything away.
+	if policy == DropOldest || policy == Reject {
+		queueSize = max(queueSize, 1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		policy: policy,
+		opts:   o,
+		queue:  make(chan Task, queueSize),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for range workers {
+		p.spawn(true)
+	}
+
+	return p
+}
+
+// Workers returns the number of running workers.
+func (p *Pool) Workers() int {
+	return int(p.workers.Load())
+}
+
+// Pending returns the number of tasks waiting in the queue.
+func (p *Pool) Pending() int {
+	return len(p.queue)
+}
+
+// Dropped returns the number of tasks discarded by the DropOldest policy or by an expired Shutdown.
+func (p *Pool) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Panics returns the number of tasks that panicked.
+func (p *Pool) Panics() int64 {
+	return p.panics.Load()
+}
+
+// Submit hands task to the pool, applying the overflow policy when the queue is full.
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	if err := p.enqueue(ctx, task); err != nil {
+		return err
+	}
+
+	p.grow()
+	return nil
+}
+
+func (p *Pool) enqueue(ctx context.Context, task Task) error {
+	switch p.policy {
+	case Reject:
+		select {
+		case p.queue <- task:
+			return nil
+		default:
+			return ErrQueueFull
+		}
+
+	case DropOldest:
+		for {
+			select {
+			case p.queue <- task:
+				return nil
+			default:
+			}
+
+			//make room, a worker might have taken it in the meantime, that's fine too.
+			select {
+			case <-p.queue:
+				p.dropped.Add(1)
+			default:
+			}
+		}
+
+	default:
+		select {
+		case p.queue <- task:
+			return nil
+		case <-p.done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// grow starts an extra worker when tasks are waiting and the pool is allowed to grow.
+func (p *Pool) grow() {
+	for len(p.queue) > 0 {
+		n := p.workers.Load()
+		if n >= int64(p.opts.maxWorkers) {
+			return
+		}
+		if p.workers.CompareAndSwap(n, n+1) {
+			p.wg.Add(1)
+			go p.worker(false)
+			return
+		}
+	}
+}
+
+func (p *Pool) spawn(permanent bool) {
+	p.workers.Add(1)
+	p.wg.Add(1)
+	go p.worker(permanent)
+}
+
+// Shutdown stops accepting tasks and waits for the workers to drain the queue. Submitters blocked
+// on a full queue get ErrClosed. If ctx is done first the pending tasks are abandoned, running ones
+// see their ctx cancelled, and ctx.Err() is returned without waiting for them to return.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	//a blocked Submit holds mu for reading, let it go before asking for the write lock.
+	p.closeOnce.Do(func() { close(p.done) })
+
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) worker(permanent bool) {
+	defer p.wg.Done()
+	defer p.workers.Add(-1)
+
+	var idle *time.Timer
+	var idleC <-chan time.Time
+	if !permanent {
+		idle = time.NewTimer(p.opts.idle)
+		defer idle.Stop()
+		idleC = idle.C
+	}
+
+	for {
+		select {
+		case task, ok := <-p.queue:
+			if !ok {
+				return
+			}
+
+			//shutdown gave up, just empty the queue.
+			if p.ctx.Err() != nil {
+				p.dropped.Add(1)
+				continue
+			}
+
+			p.run(task)
+			if idle != nil {
+				idle.Reset(p.opts.idle)
+			}
+		case <-idleC:
+			return
+		}
+	}
+}
+
+func (p *Pool) run(task Task) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.panics.Add(1)
+			if p.opts.onPanic != nil {
+				p.opts.onPanic(r, debug.Stack())
+			}
+		}
+	}()
+
+	task(p.ctx)
+}
@@ -0,0 +1,231 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmit(t *testing.T) {
+	p := New(3, 10, Block)
+
+	var n atomic.Int64
+	for range 20 {
+		if err := p.Submit(context.Background(), func(ctx context.Context) { n.Add(1) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n.Load() != 20 {
+		t.Errorf("expected 20 tasks to run; actual %d", n.Load())
+	}
+
+	if err := p.Submit(context.Background(), func(ctx context.Context) {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v; actual %v", ErrClosed, err)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		err     error
+		dropped int64
+	}{
+		{name: "reject", policy: Reject, err: ErrQueueFull},
+		{name: "drop oldest", policy: DropOldest, dropped: 1},
+		{name: "block", policy: Block, err: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(1, 1, tt.policy)
+			release := make(chan struct{})
+			started := make(chan struct{})
+
+			//occupy the only worker, then fill the queue.
+			_ = p.Submit(context.Background(), func(ctx context.Context) {
+				close(started)
+				<-release
+			})
+			<-started
+			_ = p.Submit(context.Background(), func(ctx context.Context) {})
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+			defer cancel()
+			err := p.Submit(ctx, func(ctx context.Context) {})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v; actual %v", tt.err, err)
+			}
+
+			close(release)
+			if err := p.Shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if p.Dropped() != tt.dropped {
+				t.Errorf("expected %d dropped; actual %d", tt.dropped, p.Dropped())
+			}
+		})
+	}
+}
+
+func TestPanic(t *testing.T) {
+	var recovered atomic.Value
+	p := New(1, 1, Block, OnPanic(func(v any, stack []byte) { recovered.Store(v) }))
+
+	_ = p.Submit(context.Background(), func(ctx context.Context) { panic("boom") })
+
+	//the worker survived, so this one still runs.
+	ran := make(chan struct{})
+	_ = p.Submit(context.Background(), func(ctx context.Context) { close(ran) })
+	<-ran
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p.Panics() != 1 || recovered.Load() != "boom" {
+		t.Errorf("expected the panic to be recovered; actual %d %v", p.Panics(), recovered.Load())
+	}
+}
+
+func TestShutdownAbandons(t *testing.T) {
+	p := New(1, 5, Block)
+
+	cancelled := make(chan struct{})
+	_ = p.Submit(context.Background(), func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+	for range 3 {
+		_ = p.Submit(context.Background(), func(ctx context.Context) {})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+
+	<-cancelled
+	//the workers empty the queue after Shutdown returned.
+	deadline := time.Now().Add(time.Second)
+	for p.Dropped() != 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if p.Dropped() != 3 {
+		t.Errorf("expected the 3 pending tasks to be dropped; actual %d", p.Dropped())
+	}
+}
+
+func TestShutdownStuckTask(t *testing.T) {
+	p := New(1, 0, Block)
+
+	//a task that ignores its ctx.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	_ = p.Submit(context.Background(), func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	//blocked on the full queue, holding the read lock.
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- p.Submit(context.Background(), func(ctx context.Context) {})
+	}()
+	time.Sleep(time.Millisecond * 20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- p.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-shutdown:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v; actual %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Shutdown to return once ctx expired")
+	}
+
+	select {
+	case err := <-submitted:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("expected %v; actual %v", ErrClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the blocked Submit to be woken up")
+	}
+}
+
+func TestQueueSize(t *testing.T) {
+	for _, policy := range []Policy{DropOldest, Reject} {
+		p := New(1, 0, policy)
+
+		//the single worker is busy, the task has to wait in the queue.
+		started := make(chan struct{})
+		release := make(chan struct{})
+		_ = p.Submit(context.Background(), func(ctx context.Context) {
+			close(started)
+			<-release
+		})
+		<-started
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- p.Submit(context.Background(), func(ctx context.Context) {})
+		}()
+
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Errorf("policy %d: expected the task to be queued; actual %v", policy, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("policy %d: expected Submit to return", policy)
+		}
+
+		close(release)
+		if err := p.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGrow(t *testing.T) {
+	p := New(1, 10, Block, WithMaxWorkers(3, time.Millisecond*20))
+
+	release := make(chan struct{})
+	for range 5 {
+		_ = p.Submit(context.Background(), func(ctx context.Context) { <-release })
+	}
+
+	if p.Workers() != 3 {
+		t.Errorf("expected the pool to grow to 3 workers; actual %d", p.Workers())
+	}
+
+	close(release)
+
+	//the extra workers go away once idle.
+	deadline := time.Now().Add(time.Second)
+	for p.Workers() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the pool to shrink back to 1 worker; actual %d", p.Workers())
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
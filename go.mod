@@ -3,6 +3,8 @@ module networking
 go 1.23.2
 
 require (
+	go.opentelemetry.io/otel v1.34.0
+	go.opentelemetry.io/otel/trace v1.34.0
 	golang.org/x/net v0.32.0
 	golang.org/x/sys v0.28.0
 )
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultAcceptBackoff    = 5 * time.Millisecond
+	defaultMaxAcceptBackoff = time.Second
+)
+
+// temporaryAcceptError reports whether accepting can succeed again later: out of file
+// descriptors or buffers, a connection aborted before it was accepted, or a timeout.
+func temporaryAcceptError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM, syscall.ECONNABORTED} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// acceptBackoff waits before the next Accept after a temporary error, doubling the wait on
+// every consecutive failure up to max.
+type acceptBackoff struct {
+	min, max time.Duration
+	delay    time.Duration
+}
+
+// wait sleeps for the next delay, or until ctx is done, and reports whether it slept it all.
+func (b *acceptBackoff) wait(ctx context.Context) bool {
+	if b.delay == 0 {
+		b.delay = b.min
+	} else {
+		b.delay = min(b.delay*2, b.max)
+	}
+
+	t := time.NewTimer(b.delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// reset starts over from min after a successful Accept.
+func (b *acceptBackoff) reset() {
+	b.delay = 0
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// connHandler serves one connection, the signature of Server.Handler.
+type connHandler func(ctx context.Context, conn net.Conn)
+
+// sniff gives a matcher the protocol negotiated with ALPN, empty without TLS or ALPN, and the
+// first bytes sent by the client, which are only read when a matcher asks for them.
+type sniff struct {
+	proto string
+	peek  func() []byte
+}
+
+// matcher reports whether a connection belongs to a route.
+type matcher func(s sniff) bool
+
+// matchALPN matches connections that negotiated one of protos.
+func matchALPN(protos ...string) matcher {
+	return func(s sniff) bool {
+		return slices.Contains(protos, s.proto)
+	}
+}
+
+// matchPrefix matches connections whose first bytes start with one of prefixes.
+func matchPrefix(prefixes ...string) matcher {
+	return func(s sniff) bool {
+		first := s.peek()
+		for _, p := range prefixes {
+			if bytes.HasPrefix(first, []byte(p)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// http2Preface opens every HTTP/2 connection made with prior knowledge.
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// matchHTTP2 matches connections that negotiated h2, or start with the HTTP/2 preface.
+func matchHTTP2() matcher {
+	alpn, preface := matchALPN("h2"), matchPrefix(http2Preface)
+	return func(s sniff) bool {
+		return alpn(s) || s.proto == "" && preface(s)
+	}
+}
+
+// matchHTTP1 matches connections that negotiated http/1.1, or start with an HTTP method.
+func matchHTTP1() matcher {
+	alpn := matchALPN("http/1.1")
+	methods := matchPrefix("GET ", "HEAD ", "POST ", "PUT ", "DELETE ", "CONNECT ", "OPTIONS ", "TRACE ", "PATCH ")
+	return func(s sniff) bool {
+		return alpn(s) || s.proto == "" && methods(s)
+	}
+}
+
+type muxRoute struct {
+	match   matcher
+	handler connHandler
+}
+
+/*
+connMux lets a single TLS port host several protocols: set its Serve as the Server's Handler
+and every connection goes to the first route that matches it, the fallback otherwise.
+
+ALPN is checked first and costs nothing, the first bytes are only read when a matcher needs
+them, waiting up to the sniff timeout for a client that connects and stays silent. The handler
+then reads those bytes again, but gets a wrapped conn instead of the *tls.Conn.
+*/
+type connMux struct {
+	timeout  time.Duration
+	routes   []muxRoute
+	fallback connHandler
+}
+
+// newConnMux creates a connMux waiting up to timeout for the first bytes, fallback serves the
+// connections no route matched, nil closes them.
+func newConnMux(timeout time.Duration, fallback connHandler) *connMux {
+	if fallback == nil {
+		fallback = func(ctx context.Context, conn net.Conn) {}
+	}
+	return &connMux{timeout: timeout, fallback: fallback}
+}
+
+// Handle adds a route, routes are tried in the order they were added.
+func (m *connMux) Handle(match matcher, handler connHandler) {
+	m.routes = append(m.routes, muxRoute{match: match, handler: handler})
+}
+
+func (m *connMux) Serve(ctx context.Context, conn net.Conn) {
+	var s sniff
+	if tc, ok := conn.(*tls.Conn); ok {
+		//the handshake is what tells the negotiated protocol.
+		hctx, cancel := context.WithTimeout(ctx, m.timeout)
+		err := tc.HandshakeContext(hctx)
+		cancel()
+		if err != nil {
+			return
+		}
+		s.proto = tc.ConnectionState().NegotiatedProtocol
+	}
+
+	var r *bufio.Reader
+	var first []byte
+	s.peek = func() []byte {
+		if r != nil {
+			return first
+		}
+
+		r = bufio.NewReader(conn)
+		_ = conn.SetReadDeadline(time.Now().Add(m.timeout))
+		//whatever the client sent in its first write, without waiting for more.
+		if _, err := r.Peek(1); err == nil {
+			first, _ = r.Peek(r.Buffered())
+		}
+		_ = conn.SetReadDeadline(time.Time{})
+		return first
+	}
+
+	handler := m.fallback
+	for _, route := range m.routes {
+		if route.match(s) {
+			handler = route.handler
+			break
+		}
+	}
+
+	if r != nil {
+		conn = &bufferedConn{Conn: conn, r: r}
+	}
+	handler(ctx, conn)
+}
+
+/*
+httpRoute serves the connections a connMux routes to it with an http.Server, which keeps
+handling them until they're closed. Set the server's TLSConfig with h2 in NextProtos for it to
+speak HTTP/2 on the connections that negotiated it.
+*/
+type httpRoute struct {
+	srv *http.Server
+	l   *connListener
+
+	mu     sync.Mutex
+	closed map[net.Conn]chan struct{}
+}
+
+func newHTTPRoute(srv *http.Server) *httpRoute {
+	h := &httpRoute{
+		srv:    srv,
+		l:      &connListener{conns: make(chan net.Conn), done: make(chan struct{})},
+		closed: make(map[net.Conn]chan struct{}),
+	}
+
+	hook := srv.ConnState
+	srv.ConnState = func(conn net.Conn, state http.ConnState) {
+		if hook != nil {
+			hook(conn, state)
+		}
+		//a hijacked conn is still in use, its hijacker releases it.
+		if state == http.StateClosed {
+			h.release(conn)
+		}
+	}
+
+	go func() { _ = srv.Serve(h.l) }()
+	return h
+}
+
+// Serve hands conn to the http.Server and returns once it's done with it. A hijacked
+// connection is left to its hijacker until it's released or ctx is done.
+func (h *httpRoute) Serve(ctx context.Context, conn net.Conn) {
+	done := make(chan struct{})
+	h.mu.Lock()
+	h.closed[conn] = done
+	h.mu.Unlock()
+
+	select {
+	case h.l.conns <- conn:
+	case <-h.l.done:
+		h.release(conn)
+		return
+	case <-ctx.Done():
+		h.release(conn)
+		return
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (h *httpRoute) release(conn net.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if done, ok := h.closed[conn]; ok {
+		close(done)
+		delete(h.closed, conn)
+	}
+}
+
+// Shutdown gracefully shuts the http.Server down, see http.Server.Shutdown.
+func (h *httpRoute) Shutdown(ctx context.Context) error {
+	return h.srv.Shutdown(ctx)
+}
+
+// connListener is a net.Listener accepting the connections handed to it by an httpRoute.
+type connListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return &net.TCPAddr{}
+}
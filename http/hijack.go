@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// bufferedConn is a net.Conn whose reads first drain the bytes the http server had already
+// buffered before the connection was hijacked.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// idleConn pushes the deadline of conn back by idle on every read and write, so only a
+// connection that goes quiet for idle times out.
+type idleConn struct {
+	net.Conn
+	idle time.Duration
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	if err := c.Conn.SetDeadline(time.Now().Add(c.idle)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *idleConn) Write(p []byte) (int, error) {
+	if err := c.Conn.SetDeadline(time.Now().Add(c.idle)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(p)
+}
+
+// hijack takes over the raw connection behind w for handlers that speak their own protocol
+// (WebSocket, CONNECT, custom upgrades) and hands it to fn.
+//
+// Bytes the client already sent and the server buffered are not lost, pending writes are flushed,
+// the server's read/write deadlines are replaced with an idle timeout pushed back on every read
+// and write (none when zero), and the connection is closed once fn returns, even when it panics,
+// in which case the panic is returned as an error. Until then Shutdown waits for the connection
+// like for any other, and closes it once its ctx is done.
+func (s *Server) hijack(w http.ResponseWriter, idle time.Duration, fn func(conn net.Conn) error) (err error) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return errors.New("response writer does not support hijacking")
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return fmt.Errorf("hijack: %w", err)
+	}
+
+	release, ok := s.trackHijacked(conn)
+	if !ok {
+		_ = conn.Close()
+		return errServerClosed
+	}
+
+	//from here on the http server no longer owns the conn, so it's on us to close it.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in hijacked handler: %v\n%s", r, debug.Stack())
+		}
+		_ = conn.Close()
+		release()
+	}()
+
+	if err := rw.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	//the server's deadlines were meant for the http exchange, not the new protocol.
+	var deadline time.Time
+	if idle > 0 {
+		deadline = time.Now().Add(idle)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("set deadline: %w", err)
+	}
+
+	var c net.Conn = conn
+	if rw.Reader.Buffered() > 0 {
+		c = &bufferedConn{Conn: c, r: rw.Reader}
+	}
+	if idle > 0 {
+		c = &idleConn{Conn: c, idle: idle}
+	}
+
+	return fn(c)
+}
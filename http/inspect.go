@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// action is what an inspector wants done with a request, the most severe one wins.
+type action int
+
+const (
+	allow action = iota
+	// tag lets the request through, the reason is added to its tags.
+	tag
+	// tarpit holds the request for a while before rejecting it, to slow scanners down.
+	tarpit
+	// block rejects the request right away.
+	block
+)
+
+// finding is the outcome of one inspector.
+type finding struct {
+	action action
+	reason string
+}
+
+// inspector looks at a request before it's served, body holds at most the limit given to inspect.
+type inspector interface {
+	Inspect(r *http.Request, body []byte) finding
+}
+
+// inspectorFunc adapts a function to an inspector.
+type inspectorFunc func(r *http.Request, body []byte) finding
+
+func (f inspectorFunc) Inspect(r *http.Request, body []byte) finding {
+	return f(r, body)
+}
+
+type tagsKey struct{}
+
+// requestTags returns the reasons inspectors tagged the request with.
+func requestTags(ctx context.Context) []string {
+	tags, _ := ctx.Value(tagsKey{}).([]string)
+	return tags
+}
+
+/*
+inspect runs the inspectors on every request and blocks, tarpits or tags it accordingly. Wrap
+a route group's handler with it to give that group its own rules.
+
+With bodyLimit > 0 the first bodyLimit bytes of the body are read for the inspectors and put
+back in front of the rest, so the handler still sees the whole body. Tarpitted requests are held
+for delay, or until the client gives up, then rejected like blocked ones.
+*/
+func inspect(bodyLimit int64, delay time.Duration, inspectors ...inspector) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body []byte
+			if bodyLimit > 0 && r.Body != nil {
+				var err error
+				body, err = io.ReadAll(io.LimitReader(r.Body, bodyLimit))
+				if err != nil {
+					http.Error(w, "Bad Request", http.StatusBadRequest)
+					return
+				}
+				r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+			}
+
+			verdict := allow
+			var tags []string
+			for _, i := range inspectors {
+				f := i.Inspect(r, body)
+				verdict = max(verdict, f.action)
+				if f.action == tag {
+					tags = append(tags, f.reason)
+				}
+			}
+
+			switch verdict {
+			case tarpit:
+				t := time.NewTimer(delay)
+				defer t.Stop()
+				select {
+				case <-t.C:
+				case <-r.Context().Done():
+				}
+				fallthrough
+			case block:
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			if len(tags) > 0 {
+				r = r.WithContext(context.WithValue(r.Context(), tagsKey{}, tags))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// pathTraversal blocks paths and queries trying to climb out of the served tree, including
+// their percent-encoded forms.
+func pathTraversal() inspector {
+	return inspectorFunc(func(r *http.Request, body []byte) finding {
+		for _, raw := range []string{r.URL.EscapedPath(), r.URL.RawQuery} {
+			//decode twice to catch double encoding like %252e%252e.
+			s := raw
+			for range 2 {
+				if decoded, err := url.PathUnescape(s); err == nil {
+					s = decoded
+				}
+			}
+
+			//every query value is checked like a path segment.
+			s = strings.NewReplacer(`\`, "/", "=", "/", "&", "/").Replace(s)
+			if strings.Contains("/"+s+"/", "/../") {
+				return finding{action: block, reason: "path traversal"}
+			}
+		}
+
+		return finding{}
+	})
+}
+
+// oversizedHeaders blocks requests whose header names and values add up to more than limit bytes.
+func oversizedHeaders(limit int) inspector {
+	return inspectorFunc(func(r *http.Request, body []byte) finding {
+		size := 0
+		for name, values := range r.Header {
+			for _, v := range values {
+				size += len(name) + len(v)
+			}
+		}
+
+		if size > limit {
+			return finding{action: block, reason: "oversized headers"}
+		}
+		return finding{}
+	})
+}
+
+// badUserAgents applies a to requests whose User-Agent contains one of agents, case insensitive.
+// Scanners are usually worth a tarpit, crawlers a tag.
+func badUserAgents(a action, agents ...string) inspector {
+	return inspectorFunc(func(r *http.Request, body []byte) finding {
+		ua := strings.ToLower(r.UserAgent())
+		for _, agent := range agents {
+			if strings.Contains(ua, strings.ToLower(agent)) {
+				return finding{action: a, reason: "user agent " + agent}
+			}
+		}
+
+		return finding{}
+	})
+}
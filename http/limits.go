@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"networking/concurrency-patterns/semaphore"
+)
+
+// errConnLimit is returned by acquireIP when a remote IP is at its limit with RejectExcess.
+var errConnLimit = errors.New("connection limit reached")
+
+// ExcessPolicy decides what happens to connections over the Server's limits.
+type ExcessPolicy int
+
+const (
+	// RejectExcess closes them right after accepting, before the TLS handshake.
+	RejectExcess ExcessPolicy = iota
+	// QueueExcess makes them wait: over the global limit the server stops accepting and lets
+	// the kernel backlog fill up, over the per-IP limit the accepted connection waits for one
+	// of its IP's to end. A queued connection holds its global slot while it waits.
+	QueueExcess
+)
+
+// connLimiter caps the connections a Server handles, globally and per remote IP.
+type connLimiter struct {
+	policy ExcessPolicy
+	//nil when unlimited.
+	global *semaphore.Semaphore
+	perIP  int
+
+	mu  sync.Mutex
+	ips map[string]*ipSlot
+}
+
+// ipSlot is the semaphore of one remote IP, dropped once no connection uses it.
+type ipSlot struct {
+	sem  *semaphore.Semaphore
+	refs int
+}
+
+// newConnLimiter returns nil when neither limit is set, 0 means unlimited for each.
+func newConnLimiter(total, perIP int, policy ExcessPolicy) *connLimiter {
+	if total <= 0 && perIP <= 0 {
+		return nil
+	}
+
+	l := &connLimiter{policy: policy, perIP: perIP, ips: make(map[string]*ipSlot)}
+	if total > 0 {
+		l.global = semaphore.New(int64(total))
+	}
+	return l
+}
+
+// beforeAccept waits for a global slot with QueueExcess, so the server doesn't accept past the limit.
+func (l *connLimiter) beforeAccept(ctx context.Context) error {
+	if l == nil || l.global == nil || l.policy != QueueExcess {
+		return nil
+	}
+	return l.global.Acquire(ctx, 1)
+}
+
+// admit takes the global slot of an accepted connection with RejectExcess, and reports whether
+// it may go on. With QueueExcess the slot was already taken by beforeAccept.
+func (l *connLimiter) admit() bool {
+	if l == nil || l.global == nil || l.policy != RejectExcess {
+		return true
+	}
+	return l.global.TryAcquire(1)
+}
+
+// unreserve gives back the global slot taken by beforeAccept when nothing was accepted.
+func (l *connLimiter) unreserve() {
+	if l == nil || l.global == nil || l.policy != QueueExcess {
+		return
+	}
+	l.global.Release(1)
+}
+
+// release gives back the global slot of a connection that was admitted.
+func (l *connLimiter) release() {
+	if l == nil || l.global == nil {
+		return
+	}
+	l.global.Release(1)
+}
+
+// acquireIP takes a slot for the remote IP of conn, waiting for one with QueueExcess.
+func (l *connLimiter) acquireIP(ctx context.Context, conn net.Conn) (func(), error) {
+	if l == nil || l.perIP <= 0 {
+		return func() {}, nil
+	}
+
+	ip := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	l.mu.Lock()
+	slot, ok := l.ips[ip]
+	if !ok {
+		slot = &ipSlot{sem: semaphore.New(int64(l.perIP))}
+		l.ips[ip] = slot
+	}
+	slot.refs++
+	l.mu.Unlock()
+
+	drop := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		slot.refs--
+		if slot.refs == 0 {
+			delete(l.ips, ip)
+		}
+	}
+
+	if l.policy == RejectExcess {
+		if !slot.sem.TryAcquire(1) {
+			drop()
+			return nil, errConnLimit
+		}
+	} else if err := slot.sem.Acquire(ctx, 1); err != nil {
+		drop()
+		return nil, err
+	}
+
+	return func() {
+		slot.sem.Release(1)
+		drop()
+	}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// Family picks the IP versions a Server listens on.
+type Family int
+
+const (
+	// DualStack accepts IPv4 and IPv6 on a wildcard address, IPv4 peers show up as
+	// IPv4-mapped IPv6 addresses. It clears IPV6_V6ONLY instead of trusting the platform default.
+	DualStack Family = iota
+	// IPv4Only binds an IPv4 socket.
+	IPv4Only
+	// IPv6Only binds an IPv6 socket with IPV6_V6ONLY set, so IPv4 peers can't reach it.
+	IPv6Only
+)
+
+func (f Family) String() string {
+	switch f {
+	case DualStack:
+		return "dual-stack"
+	case IPv4Only:
+		return "ipv4"
+	case IPv6Only:
+		return "ipv6"
+	default:
+		return fmt.Sprintf("Family(%d)", int(f))
+	}
+}
+
+// network returns the network net.Listen is called with.
+func (f Family) network() string {
+	switch f {
+	case IPv4Only:
+		return "tcp4"
+	case IPv6Only:
+		return "tcp6"
+	default:
+		return "tcp"
+	}
+}
+
+// listen binds addr for family. On a host without IPv6 the dual stack listener falls back to
+// IPv4 on its own, IPv6Only fails.
+func listen(ctx context.Context, family Family, addr string) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			//only IPv6 sockets have the option.
+			if network != "tcp6" {
+				return nil
+			}
+
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = setV6Only(fd, family == IPv6Only)
+			})
+			if err != nil {
+				return err
+			}
+			if sockErr != nil {
+				return fmt.Errorf("setting IPV6_V6ONLY: %w", sockErr)
+			}
+			return nil
+		},
+	}
+
+	return lc.Listen(ctx, family.network(), addr)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// setV6Only leaves the platform default in place outside unix.
+func setV6Only(fd uintptr, only bool) error {
+	return nil
+}
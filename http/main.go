@@ -10,12 +10,14 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"net"
 	"net/http"
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -59,27 +61,67 @@ func withPusher(w http.ResponseWriter, r *http.Request) {
 }
 
 type Server struct {
-	ctx       context.Context
+	ctx context.Context
+	//closed the first time the server is up, see WithReady.
 	ready     chan struct{}
 	addr      string
 	maxIdle   time.Duration
 	tlsConfig *tls.Config
+	logger    *slog.Logger
+
+	maxConns      int
+	maxConnsPerIP int
+	excess        ExcessPolicy
+	limiter       *connLimiter
+
+	backoff       acceptBackoff
+	onAcceptError func(err error)
+
+	//cleartext companion listener, see WithHTTPRedirect.
+	redirectAddr string
+	acme         func(http.Handler) http.Handler
+	redirect     *http.Server
+
+	//set by ServeHTTP.
+	httpHandler *swapHandler
+	httpServer  *http.Server
+	httpRoute   *httpRoute
+
+	//IP versions to listen on, both by default.
+	Family Family
+
+	//serves every accepted connection, echo by default. ctx is cancelled by Shutdown, which
+	//also interrupts a pending read, and conn is closed once Handler returns.
+	Handler func(ctx context.Context, conn net.Conn)
+
+	//tracks the listener and connections so Shutdown can drain them.
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	stop     context.CancelFunc
+	closed   bool
+	wg       sync.WaitGroup
+
+	//readiness of the current ListenAndServeTLS or ServeTLS call, see WaitReady.
+	readyCh      chan struct{}
+	readyErr     error
+	readySettled bool
 }
 
-func NewTLSServer(ctx context.Context, address string, maxIdle time.Duration, tlsConf *tls.Config) *Server {
-	return &Server{
-		ctx:       ctx,
-		ready:     make(chan struct{}),
-		addr:      address,
-		maxIdle:   maxIdle,
-		tlsConfig: tlsConf,
+// NewServer creates a Server for address, localhost:443 when empty, that echoes over TLS
+// unless Handler is set.
+func NewServer(address string, opts ...Option) *Server {
+	s := &Server{
+		addr:    address,
+		logger:  slog.Default(),
+		backoff: acceptBackoff{min: defaultAcceptBackoff, max: defaultMaxAcceptBackoff},
 	}
-}
-
-func (s *Server) Ready() {
-	if s.ready != nil {
-		<-s.ready
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.limiter = newConnLimiter(s.maxConns, s.maxConnsPerIP, s.excess)
+
+	return s
 }
 
 func (s *Server) ListenAndServeTLS(cert, key string) error {
@@ -87,16 +129,33 @@ func (s *Server) ListenAndServeTLS(cert, key string) error {
 		s.addr = "localhost:443"
 	}
 
-	l, err := net.Listen("tcp", s.addr)
+	s.starting()
+	if err := s.Preflight(cert, key); err != nil {
+		err = fmt.Errorf("preflight: %w", err)
+		s.started(err)
+		return err
+	}
+
+	l, err := listen(context.Background(), s.Family, s.addr)
 	if err != nil {
-		return fmt.Errorf("binding tcp %s: %w", s.addr, err)
+		err = listenError(s.addr, err)
+		s.started(err)
+		return err
 	}
 
+	if err := s.serveRedirect(); err != nil {
+		_ = l.Close()
+		s.started(err)
+		return err
+	}
+	defer s.stopRedirect()
+
 	if s.ctx != nil {
 		go func() {
 			<-s.ctx.Done()
-			//close
-			_ = l.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+			defer cancel()
+			_ = s.Shutdown(ctx)
 		}()
 	}
 
@@ -104,6 +163,8 @@ func (s *Server) ListenAndServeTLS(cert, key string) error {
 }
 
 func (s *Server) ServeTLS(l net.Listener, cert, key string) error {
+	s.starting()
+
 	if s.tlsConfig == nil {
 		s.tlsConfig = &tls.Config{
 			CurvePreferences:         []tls.CurveID{tls.CurveP256},
@@ -118,7 +179,9 @@ func (s *Server) ServeTLS(l net.Listener, cert, key string) error {
 	if len(s.tlsConfig.Certificates) == 0 && s.tlsConfig.GetCertificate == nil {
 		certificate, err := tls.LoadX509KeyPair(cert, key)
 		if err != nil {
-			return fmt.Errorf("loading key pair: %w", err)
+			err = fmt.Errorf("loading key pair: %w", err)
+			s.started(err)
+			return err
 		}
 
 		// Add the loaded certificate to the TLS configuration
@@ -126,47 +189,131 @@ func (s *Server) ServeTLS(l net.Listener, cert, key string) error {
 
 	}
 
+	httpHandler := s.httpMode()
+	if httpHandler != nil {
+		s.withALPN()
+	}
+
 	listenerTLS := tls.NewListener(l, s.tlsConfig)
-	if s.ready != nil {
-		close(s.ready)
+	ctx, ok := s.serving(listenerTLS)
+	if !ok {
+		_ = l.Close()
+		s.started(errServerClosed)
+		return nil
+	}
+	s.started(nil)
+
+	handler := s.Handler
+	if handler == nil {
+		handler = s.echo
+	}
+
+	//HTTP connections are drained by the http.Server on Shutdown, not interrupted.
+	interrupt := true
+	if httpHandler != nil {
+		var stop func()
+		handler, stop = s.serveHTTP(ctx, httpHandler)
+		defer stop()
+		interrupt = false
 	}
 
 	for {
+		if err := s.limiter.beforeAccept(ctx); err != nil {
+			//only fails once Shutdown cancelled ctx.
+			return nil
+		}
+
 		// Since we are using a TLS-aware listener, it returns connection objects with
 		// underlying TLS support
 		conn, err := listenerTLS.Accept()
 		if err != nil {
-			return fmt.Errorf("accept: %w", err)
+			if s.shuttingDown() {
+				return nil
+			}
+			if s.onAcceptError != nil {
+				s.onAcceptError(err)
+			}
+			if !temporaryAcceptError(err) {
+				return fmt.Errorf("accept: %w", err)
+			}
+
+			s.limiter.unreserve()
+			s.logger.Warn("accept failed, retrying", "err", err)
+			if !s.backoff.wait(ctx) {
+				return nil
+			}
+			continue
+		}
+		s.backoff.reset()
+
+		if !s.limiter.admit() {
+			s.logger.Debug("connection over the limit", "remote", conn.RemoteAddr())
+			_ = conn.Close()
+			continue
+		}
+
+		if !s.track(conn) {
+			s.limiter.release()
+			_ = conn.Close()
+			continue
 		}
 
 		//handler
 		go func() {
+			defer s.untrack(conn)
+			defer s.limiter.release()
 			defer func() { _ = conn.Close() }()
 
-			for {
-				if s.maxIdle > 0 {
-					//set the deadline on conn
-					if err := conn.SetDeadline(time.Now().Add(s.maxIdle)); err != nil {
-						return
-					}
-				}
-
-				buf := make([]byte, 1024)
-				//this is a blocking call so we only wait till the deadline exceeds
-				n, err := conn.Read(buf)
-				if err != nil {
-					return
-				}
-
-				_, err = conn.Write(buf[:n])
-				if err != nil {
-					return
-				}
+			releaseIP, err := s.limiter.acquireIP(ctx, conn)
+			if err != nil {
+				s.logger.Debug("connection over the per-IP limit", "remote", conn.RemoteAddr())
+				return
+			}
+			defer releaseIP()
+
+			//a cancelled ctx interrupts the pending read, never a write half way.
+			if interrupt {
+				stop := context.AfterFunc(ctx, func() {
+					_ = conn.SetReadDeadline(time.Now())
+				})
+				defer stop()
 			}
+
+			handler(ctx, conn)
 		}()
 	}
 }
 
+// echo writes back whatever the client sends until the client goes idle for maxIdle or ctx is
+// cancelled by Shutdown.
+func (s *Server) echo(ctx context.Context, conn net.Conn) {
+	for {
+		if s.maxIdle > 0 {
+			//set the deadline on conn
+			if err := conn.SetDeadline(time.Now().Add(s.maxIdle)); err != nil {
+				return
+			}
+		}
+
+		//checked after the deadline is set, so it can't overwrite the one set by Shutdown.
+		if ctx.Err() != nil {
+			return
+		}
+
+		buf := make([]byte, 1024)
+		//this is a blocking call so we only wait till the deadline exceeds
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+
+		_, err = conn.Write(buf[:n])
+		if err != nil {
+			return
+		}
+	}
+}
+
 func generatingCertificate(hosts []string, certFilename, privateFilename string) error {
 
 	//generates a random number between [0,max-1], here it is [0,1*2^128]
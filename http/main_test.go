@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -12,11 +14,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"runtime"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"golang.org/x/net/http2"
+
+	"networking/concurrency-patterns/workerpool"
+	"networking/http/middleware"
 )
 
 func TestSimpleHTTPServer(t *testing.T) {
@@ -83,7 +91,7 @@ func TestSimpleMuxWithMiddleware(t *testing.T) {
 	})
 
 	//apply middlewares
-	mux := applyMiddlewares(serveMux, drainAndClose)
+	mux := middleware.Apply(serveMux, middleware.DrainAndClose)
 
 	testCases := []struct {
 		path     string
@@ -123,29 +131,6 @@ func TestSimpleMuxWithMiddleware(t *testing.T) {
 	}
 }
 
-func drainAndClose(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		//call next handler
-		next.ServeHTTP(w, r)
-
-		//drain body
-		_, _ = io.Copy(io.Discard, r.Body)
-		_ = r.Body.Close()
-	})
-}
-
-type Middleware func(http.Handler) http.Handler
-
-func applyMiddlewares(mux http.Handler, mids ...Middleware) http.Handler {
-	for i := len(mids) - 1; i >= 0; i-- {
-		m := mids[i]
-		if m != nil {
-			mux = m(mux)
-		}
-	}
-	return mux
-}
-
 func TestClientTLS(t *testing.T) {
 	ts := httptest.NewTLSServer(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -239,7 +224,7 @@ func TestEchoTLSServer(t *testing.T) {
 
 	serverAddr := "localhost:34443"
 	maxIdle := time.Second
-	server := NewTLSServer(ctx, serverAddr, maxIdle, nil)
+	server := NewServer(serverAddr, WithContext(ctx), WithIdleTimeout(maxIdle))
 
 	done := make(chan struct{})
 
@@ -254,7 +239,9 @@ func TestEchoTLSServer(t *testing.T) {
 
 	}()
 	//waits for server to become ready
-	server.Ready()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
 
 	//pinning certificate
 	cert, err := os.ReadFile("cert.pem")
@@ -432,7 +419,7 @@ func TestMutualTLSAuthentication(t *testing.T) {
 		},
 	}
 	serverAddress := "localhost:44443"
-	server := NewTLSServer(ctx, serverAddress, 0, serverConfigs)
+	server := NewServer(serverAddress, WithContext(ctx), WithTLSConfig(serverConfigs))
 	done := make(chan struct{})
 
 	go func() {
@@ -445,7 +432,9 @@ func TestMutualTLSAuthentication(t *testing.T) {
 		done <- struct{}{}
 	}()
 
-	server.Ready()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
 
 	//take care client
 	clientPool, err := caCertPool("serverCert.pem")
@@ -496,3 +485,1304 @@ func TestMutualTLSAuthentication(t *testing.T) {
 	<-done
 
 }
+
+func TestHijack(t *testing.T) {
+	server := NewServer("127.0.0.1:0")
+	errCh := make(chan error, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errCh <- server.hijack(w, time.Second, func(conn net.Conn) error {
+			_, _ = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n\r\n"))
+
+			//the client sent its first message along with the request, it must not be lost.
+			buf := make([]byte, 4)
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return err
+			}
+
+			if _, err := conn.Write(buf); err != nil {
+				return err
+			}
+
+			panic("boom")
+		})
+	}))
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\nping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasSuffix(b, []byte("\r\n\r\nping")) {
+		t.Fatalf("expected echoed message after the upgrade; actual %q", b)
+	}
+
+	if err := <-errCh; err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected panic to be returned as an error; actual %v", err)
+	}
+}
+
+func TestPreflightKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	certA, keyA := dir+"/a.pem", dir+"/a.key"
+	certB, keyB := dir+"/b.pem", dir+"/b.key"
+
+	if err := generatingCertificate([]string{"localhost"}, certA, keyA); err != nil {
+		t.Fatal(err)
+	}
+	if err := generatingCertificate([]string{"localhost"}, certB, keyB); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer("127.0.0.1:0")
+
+	if err := server.Preflight(certA, keyA); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.Preflight(certA, keyB); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("expected mismatched key pair error; actual %v", err)
+	}
+
+	if err := server.Preflight(dir+"/missing.pem", keyA); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected %v; actual %v", os.ErrNotExist, err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	handler := versionHandler([]string{"tls", "hijack"}, []string{"http/1.1", "h2"})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d; actual %d", http.StatusOK, w.Code)
+	}
+
+	var v buildVersion
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Go != runtime.Version() {
+		t.Errorf("expected go %s; actual %s", runtime.Version(), v.Go)
+	}
+	if strings.Join(v.Subsystems, ",") != "tls,hijack" || strings.Join(v.Protocols, ",") != "http/1.1,h2" {
+		t.Errorf("unexpected subsystems or protocols: %+v", v)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/version", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d; actual %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+// TestMutualTLSRejection walks through mTLS from the server's side: a client presenting a
+// certificate signed by a trusted CA gets in, one without a certificate or with a certificate
+// the server doesn't trust is rejected during the handshake.
+func TestMutualTLSRejection(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name string) string { return dir + "/" + name }
+
+	for _, name := range []string{"server", "client", "stranger"} {
+		if err := generatingCertificate([]string{"127.0.0.1"}, file(name+"Cert.pem"), file(name+"Key.pem")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clientCAs, err := caCertPool(file("clientCert.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCAs, err := caCertPool(file("serverCert.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCert, err := tls.LoadX509KeyPair(file("serverCert.pem"), file("serverKey.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello " + r.TLS.PeerCertificates[0].Subject.Organization[0]))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS13,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	clientWith := func(certName string) *http.Client {
+		conf := &tls.Config{RootCAs: serverCAs, MinVersion: tls.VersionTLS13}
+		if certName != "" {
+			cert, err := tls.LoadX509KeyPair(file(certName+"Cert.pem"), file(certName+"Key.pem"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf.Certificates = []tls.Certificate{cert}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: conf}}
+	}
+
+	testCases := []struct {
+		name    string
+		cert    string
+		allowed bool
+	}{
+		{name: "trusted client", cert: "client", allowed: true},
+		{name: "no certificate", cert: ""},
+		{name: "untrusted certificate", cert: "stranger"},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			resp, err := clientWith(c.cert).Get(srv.URL)
+			if !c.allowed {
+				if err == nil {
+					_ = resp.Body.Close()
+					t.Fatal("expected the handshake to be rejected")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != "hello Foo Bar" {
+				t.Errorf("expected %q; actual %q", "hello Foo Bar", body)
+			}
+		})
+	}
+}
+
+// TestListenFamily binds the wildcard address with every Family and checks which loopback
+// addresses can reach it, the IPv6 cases are skipped on hosts without IPv6.
+func TestListenFamily(t *testing.T) {
+	ipv6 := true
+	if l, err := net.Listen("tcp6", "[::1]:0"); err != nil {
+		ipv6 = false
+	} else {
+		_ = l.Close()
+	}
+
+	testCases := []struct {
+		family Family
+		v4, v6 bool
+	}{
+		{family: DualStack, v4: true, v6: true},
+		{family: IPv4Only, v4: true},
+		{family: IPv6Only, v6: true},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.family.String(), func(t *testing.T) {
+			if c.family == IPv6Only && !ipv6 {
+				t.Skip("no IPv6 on this host")
+			}
+
+			l, err := listen(context.Background(), c.family, ":0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			go func() {
+				for {
+					conn, err := l.Accept()
+					if err != nil {
+						return
+					}
+					_ = conn.Close()
+				}
+			}()
+
+			port := l.Addr().(*net.TCPAddr).Port
+			reachable := func(host string) bool {
+				conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, fmt.Sprint(port)), time.Second)
+				if err != nil {
+					return false
+				}
+				_ = conn.Close()
+				return true
+			}
+
+			if actual := reachable("127.0.0.1"); actual != c.v4 {
+				t.Errorf("expected IPv4 reachable %t; actual %t", c.v4, actual)
+			}
+			if !ipv6 {
+				return
+			}
+			if actual := reachable("::1"); actual != c.v6 {
+				t.Errorf("expected IPv6 reachable %t; actual %t", c.v6, actual)
+			}
+		})
+	}
+}
+
+func TestSwapHandler(t *testing.T) {
+	text := func(s string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(s))
+		})
+	}
+
+	swap := newSwapHandler(text("v1"))
+	srv := httptest.NewServer(swap)
+	defer srv.Close()
+
+	get := func() string {
+		resp, err := srv.Client().Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if actual := get(); actual != "v1" {
+		t.Fatalf("expected %q; actual %q", "v1", actual)
+	}
+
+	//reload without touching the listener, the keep-alive connection sees the new tree too.
+	swap.Swap(text("v2"))
+	if actual := get(); actual != "v2" {
+		t.Errorf("expected %q; actual %q", "v2", actual)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(t *testing.T) (*Server, string, chan error) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		server := NewServer(l.Addr().String())
+		served := make(chan error, 1)
+		go func() { served <- server.ServeTLS(l, cert, key) }()
+		if err := server.WaitReady(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		return server, l.Addr().String(), served
+	}
+
+	dial := func(t *testing.T, addr string) *tls.Conn {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+
+		//make sure the server is handling it before shutting down.
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadFull(conn, make([]byte, 4)); err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	t.Run("drains idle connections", func(t *testing.T) {
+		server, addr, served := start(t)
+		conn := dial(t, addr)
+
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-served; err != nil {
+			t.Errorf("expected ServeTLS to return nil; actual %v", err)
+		}
+		if _, err := conn.Read(make([]byte, 1)); err == nil {
+			t.Error("expected the connection to be closed")
+		}
+		if _, err := net.Dial("tcp", addr); err == nil {
+			t.Error("expected the listener to be closed")
+		}
+	})
+
+	t.Run("force closes stragglers", func(t *testing.T) {
+		server, addr, served := start(t)
+		conn := dial(t, addr)
+
+		//never read the echoes, the server ends up stuck writing them.
+		go func() {
+			buf := make([]byte, 64*1024)
+			for {
+				if _, err := conn.Write(buf); err != nil {
+					return
+				}
+			}
+		}()
+		time.Sleep(time.Millisecond * 200)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		defer cancel()
+		if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v; actual %v", context.DeadlineExceeded, err)
+		}
+		if err := <-served; err != nil {
+			t.Errorf("expected ServeTLS to return nil; actual %v", err)
+		}
+	})
+}
+
+func TestServerHandler(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(l.Addr().String())
+	//a protocol other than echo on the same skeleton.
+	server.Handler = func(ctx context.Context, conn net.Conn) {
+		buf := make([]byte, 1024)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write(bytes.ToUpper(buf[:n]))
+	}
+
+	go func() { _ = server.ServeTLS(l, cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown(context.Background())
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	//the handler returned, so the server closed the connection after answering.
+	resp, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != "HELLO" {
+		t.Errorf("expected %q; actual %q", "HELLO", resp)
+	}
+}
+
+func TestBodyTee(t *testing.T) {
+	pool := workerpool.New(1, 1, workerpool.Reject)
+	defer pool.Shutdown(context.Background())
+
+	seen := make(chan teedRequest, 10)
+	tee := newBodyTee(pool, 5, func(ctx context.Context, r teedRequest) { seen <- r })
+
+	handler := tee.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("hello world")))
+
+	select {
+	case r := <-seen:
+		if string(r.Body) != "hello" || !r.Truncated || r.URL != "/upload" {
+			t.Errorf("expected the first 5 bytes of /upload; actual %q, truncated %t, %s", r.Body, r.Truncated, r.URL)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the processor to run")
+	}
+
+	//occupy the worker and fill the queue, the next request must not wait for them.
+	release := make(chan struct{})
+	_ = pool.Submit(context.Background(), func(ctx context.Context) { <-release })
+	for pool.Pending() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_ = pool.Submit(context.Background(), func(ctx context.Context) {})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x")))
+	close(release)
+
+	if tee.Rejected() != 1 {
+		t.Errorf("expected 1 rejected run; actual %d", tee.Rejected())
+	}
+}
+
+func TestNewServerOptions(t *testing.T) {
+	server := NewServer("")
+	if server.maxIdle != 0 || server.tlsConfig != nil || server.ready != nil || server.logger == nil {
+		t.Fatalf("expected the defaults; actual idle %s, tls %v, ready %v", server.maxIdle, server.tlsConfig, server.ready)
+	}
+
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ready := make(chan struct{})
+	server = NewServer(l.Addr().String(), WithIdleTimeout(time.Minute), WithReady(ready))
+	go func() { _ = server.ServeTLS(l, cert, key) }()
+	defer server.Shutdown(context.Background())
+
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+		t.Fatal("expected the server to close the ready channel")
+	}
+	if server.maxIdle != time.Minute {
+		t.Errorf("expected idle timeout %s; actual %s", time.Minute, server.maxIdle)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	//the body rule only looks at what inspect read up front.
+	noSecrets := inspectorFunc(func(r *http.Request, body []byte) finding {
+		if bytes.Contains(body, []byte("password")) {
+			return finding{action: tag, reason: "secret in body"}
+		}
+		return finding{}
+	})
+
+	var tags []string
+	var body string
+	handler := inspect(16, time.Millisecond*10,
+		pathTraversal(),
+		oversizedHeaders(64),
+		badUserAgents(tarpit, "sqlmap"),
+		badUserAgents(tag, "bot"),
+		noSecrets,
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tags = requestTags(r.Context())
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+
+	testCases := []struct {
+		name   string
+		target string
+		agent  string
+		header string
+		body   string
+		code   int
+		tags   []string
+	}{
+		{name: "clean", target: "/files/a.txt", body: "hello", code: http.StatusOK},
+		{name: "traversal", target: "/files/../etc/passwd", code: http.StatusForbidden},
+		{name: "encoded traversal", target: "/files/%2e%2e%2fetc", code: http.StatusForbidden},
+		{name: "double encoded traversal", target: "/files?f=%252e%252e/etc", code: http.StatusForbidden},
+		{name: "oversized headers", target: "/", header: strings.Repeat("a", 100), code: http.StatusForbidden},
+		{name: "scanner", target: "/", agent: "sqlmap/1.7", code: http.StatusForbidden},
+		{name: "tagged", target: "/", agent: "SomeBot/2.0", body: "user=a&password=b, the rest is past the limit", code: http.StatusOK,
+			tags: []string{"user agent bot", "secret in body"}},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			tags, body = nil, ""
+			r := httptest.NewRequest(http.MethodPost, c.target, strings.NewReader(c.body))
+			if c.agent != "" {
+				r.Header.Set("User-Agent", c.agent)
+			}
+			if c.header != "" {
+				r.Header.Set("X-Padding", c.header)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != c.code {
+				t.Fatalf("expected code %d; actual %d", c.code, w.Code)
+			}
+			if c.code != http.StatusOK {
+				return
+			}
+			if body != c.body {
+				t.Errorf("expected the handler to read %q; actual %q", c.body, body)
+			}
+			if fmt.Sprint(tags) != fmt.Sprint(c.tags) {
+				t.Errorf("expected tags %v; actual %v", c.tags, tags)
+			}
+		})
+	}
+}
+
+func TestConnLimits(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(t *testing.T, opts ...Option) string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		server := NewServer(l.Addr().String(), opts...)
+		go func() { _ = server.ServeTLS(l, cert, key) }()
+		if err := server.WaitReady(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = server.Shutdown(context.Background()) })
+
+		return l.Addr().String()
+	}
+
+	//echo reports whether the server answered within timeout.
+	echo := func(t *testing.T, conn *tls.Conn, timeout time.Duration) bool {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			return false
+		}
+		_, err := io.ReadFull(conn, make([]byte, 4))
+		return err == nil
+	}
+
+	dial := func(t *testing.T, addr string) *tls.Conn {
+		raw, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn := tls.Client(raw, &tls.Config{InsecureSkipVerify: true})
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+
+	t.Run("reject over the global limit", func(t *testing.T) {
+		addr := start(t, WithMaxConns(1))
+
+		first := dial(t, addr)
+		if !echo(t, first, time.Second) {
+			t.Fatal("expected the first connection to be served")
+		}
+		if echo(t, dial(t, addr), time.Second) {
+			t.Error("expected the second connection to be rejected")
+		}
+
+		_ = first.Close()
+		//the slot is given back once the server notices the close.
+		for i := 0; ; i++ {
+			if echo(t, dial(t, addr), time.Second) {
+				break
+			}
+			if i == 50 {
+				t.Fatal("expected a new connection to be served once the first one ended")
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	})
+
+	t.Run("queue over the per-IP limit", func(t *testing.T) {
+		addr := start(t, WithMaxConnsPerIP(1), WithExcessPolicy(QueueExcess))
+
+		first := dial(t, addr)
+		if !echo(t, first, time.Second) {
+			t.Fatal("expected the first connection to be served")
+		}
+
+		second := dial(t, addr)
+		answered := make(chan bool, 1)
+		go func() { answered <- echo(t, second, time.Second*2) }()
+
+		select {
+		case <-answered:
+			t.Fatal("expected the second connection to wait for the first one")
+		case <-time.After(time.Millisecond * 200):
+		}
+
+		_ = first.Close()
+		if !<-answered {
+			t.Error("expected the second connection to be served once the first one ended")
+		}
+	})
+}
+
+// flakyListener fails the first accepts with errs before accepting for real.
+type flakyListener struct {
+	net.Listener
+	errs chan error
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	select {
+	case err := <-l.errs:
+		return nil, err
+	default:
+		return l.Listener.Accept()
+	}
+}
+
+func TestAcceptBackoff(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("temporary errors", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		flaky := &flakyListener{Listener: l, errs: make(chan error, 3)}
+		for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ECONNABORTED, syscall.EMFILE} {
+			flaky.errs <- &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", errno)}
+		}
+
+		var seen atomic.Int64
+		server := NewServer(l.Addr().String(), WithAcceptBackoff(time.Millisecond, time.Millisecond*4),
+			OnAcceptError(func(err error) { seen.Add(1) }))
+		go func() { _ = server.ServeTLS(flaky, cert, key) }()
+		if err := server.WaitReady(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer server.Shutdown(context.Background())
+
+		conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadFull(conn, make([]byte, 4)); err != nil {
+			t.Fatalf("expected the server to survive the temporary errors: %v", err)
+		}
+		if seen.Load() != 3 {
+			t.Errorf("expected 3 accept errors; actual %d", seen.Load())
+		}
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		permanent := errors.New("listener broken")
+		flaky := &flakyListener{Listener: l, errs: make(chan error, 1)}
+		flaky.errs <- permanent
+
+		server := NewServer(l.Addr().String())
+		if err := server.ServeTLS(flaky, cert, key); !errors.Is(err, permanent) {
+			t.Errorf("expected %v; actual %v", permanent, err)
+		}
+	})
+}
+
+func TestWaitReady(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+
+	server := NewServer("127.0.0.1:0")
+
+	//no key pair yet, the failure must reach WaitReady instead of leaving it blocked.
+	failed := make(chan error, 1)
+	go func() { failed <- server.ListenAndServeTLS(cert, key) }()
+	if err := server.WaitReady(context.Background()); err == nil || !errors.Is(err, <-failed) {
+		t.Fatalf("expected the preflight error; actual %v", err)
+	}
+
+	//restart once the files exist, readiness starts over.
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.ResetReady()
+	served := make(chan error, 1)
+	go func() { served <- server.ServeTLS(l, cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-served
+
+	//a second ServeTLS after Shutdown reports it instead of panicking.
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.ServeTLS(l, cert, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.WaitReady(context.Background()); !errors.Is(err, errServerClosed) {
+		t.Errorf("expected %v; actual %v", errServerClosed, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewServer("").WaitReady(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v; actual %v", context.Canceled, err)
+	}
+}
+
+func TestHTTPRedirect(t *testing.T) {
+	testCases := []struct {
+		tlsAddr  string
+		target   string
+		location string
+	}{
+		{tlsAddr: ":443", target: "http://example.com/a?b=c", location: "https://example.com/a?b=c"},
+		{tlsAddr: ":443", target: "http://example.com:8080/", location: "https://example.com/"},
+		{tlsAddr: ":8443", target: "http://example.com/a", location: "https://example.com:8443/a"},
+		{tlsAddr: ":443", target: "http://[::1]:80/", location: "https://[::1]/"},
+	}
+
+	for _, c := range testCases {
+		w := httptest.NewRecorder()
+		redirectHandler(c.tlsAddr).ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.target, nil))
+
+		if w.Code != http.StatusMovedPermanently {
+			t.Errorf("%s: expected code %d; actual %d", c.target, http.StatusMovedPermanently, w.Code)
+		}
+		if actual := w.Header().Get("Location"); actual != c.location {
+			t.Errorf("%s: expected location %q; actual %q", c.target, c.location, actual)
+		}
+	}
+
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	freeAddr := func() string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		return l.Addr().String()
+	}
+
+	tlsAddr, httpAddr := freeAddr(), freeAddr()
+	//the ACME wrapper answers challenges itself and falls back to the redirect.
+	acme := func(fallback http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+				_, _ = w.Write([]byte("token"))
+				return
+			}
+			fallback.ServeHTTP(w, r)
+		})
+	}
+
+	server := NewServer(tlsAddr, WithHTTPRedirect(httpAddr), WithACMEHandler(acme))
+	served := make(chan error, 1)
+	go func() { served <- server.ListenAndServeTLS(cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get("http://" + httpAddr + "/path?q=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if expected := "https://" + tlsAddr + "/path?q=1"; resp.Header.Get("Location") != expected {
+		t.Errorf("expected location %q; actual %q", expected, resp.Header.Get("Location"))
+	}
+
+	resp, err = client.Get("http://" + httpAddr + "/.well-known/acme-challenge/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "token" {
+		t.Errorf("expected the challenge to be answered; actual %d %q", resp.StatusCode, body)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := net.Dial("tcp", httpAddr); err == nil {
+		t.Error("expected the redirect listener to be closed")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(l.Addr().String(), WithIdleTimeout(time.Minute))
+	server.ServeHTTP(middleware.Apply(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "v1 %s tls=%t", r.Proto, r.TLS != nil)
+	}), middleware.DrainAndClose))
+
+	served := make(chan error, 1)
+	go func() { served <- server.ServeTLS(l, cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	get := func() string {
+		resp, err := client.Get("https://" + l.Addr().String() + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if actual := get(); actual != "v1 HTTP/2.0 tls=true" {
+		t.Errorf("expected %q; actual %q", "v1 HTTP/2.0 tls=true", actual)
+	}
+
+	//mounted next to handler, with the protocols offered during the handshake.
+	resp, err := client.Get("https://" + l.Addr().String() + "/version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v buildVersion
+	err = json.NewDecoder(resp.Body).Decode(&v)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(v.Subsystems, ",") != "tls" || strings.Join(v.Protocols, ",") != "h2,http/1.1" {
+		t.Errorf("unexpected subsystems or protocols: %+v", v)
+	}
+
+	//swapped in between requests on the same listener.
+	server.ServeHTTP(versionHandler(nil, []string{"h2"}))
+	if actual := get(); !strings.Contains(actual, `"protocols":["h2"]`) {
+		t.Errorf("expected the version handler to answer; actual %q", actual)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("expected ServeTLS to return nil; actual %v", err)
+	}
+}
+
+func TestConnMux(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &tls.Config{Certificates: []tls.Certificate{pair}, NextProtos: []string{"h2", "http/1.1"}}
+
+	web := newHTTPRoute(&http.Server{
+		TLSConfig: conf,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, r.Proto)
+		}),
+	})
+	defer web.Shutdown(context.Background())
+
+	mux := newConnMux(time.Second, func(ctx context.Context, conn net.Conn) {
+		_, _ = io.Copy(conn, conn)
+	})
+	mux.Handle(matchHTTP2(), web.Serve)
+	mux.Handle(matchHTTP1(), web.Serve)
+	mux.Handle(matchPrefix("PING"), func(ctx context.Context, conn net.Conn) {
+		_, _ = io.ReadFull(conn, make([]byte, 4))
+		_, _ = conn.Write([]byte("PONG"))
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(l.Addr().String(), WithTLSConfig(conf))
+	server.Handler = mux.Serve
+	go func() { _ = server.ServeTLS(l, cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown(context.Background())
+
+	url := "https://" + l.Addr().String() + "/"
+	get := func(transport *http.Transport) string {
+		resp, err := (&http.Client{Transport: transport}).Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	//each transport gets its own config, enabling HTTP/2 adds h2 to the one it's given.
+	insecure := &tls.Config{InsecureSkipVerify: true}
+	if actual := get(&http.Transport{TLSClientConfig: insecure.Clone(), ForceAttemptHTTP2: true}); actual != "HTTP/2.0" {
+		t.Errorf("expected %q; actual %q", "HTTP/2.0", actual)
+	}
+	//an empty TLSNextProto turns HTTP/2 off.
+	http1 := &http.Transport{TLSClientConfig: insecure.Clone(), TLSNextProto: map[string]func(string, *tls.Conn) http.RoundTripper{}}
+	if actual := get(http1); actual != "HTTP/1.1" {
+		t.Errorf("expected %q; actual %q", "HTTP/1.1", actual)
+	}
+
+	raw := func(msg string) string {
+		conn, err := tls.Dial("tcp", l.Addr().String(), insecure)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		return string(buf)
+	}
+
+	if actual := raw("PING"); actual != "PONG" {
+		t.Errorf("expected %q; actual %q", "PONG", actual)
+	}
+	if actual := raw("hello"); actual != "hello" {
+		t.Errorf("expected the fallback to echo; actual %q", actual)
+	}
+}
+
+func TestServeHTTPLimits(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(l.Addr().String(), WithMaxConns(1))
+	server.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	go func() { _ = server.ServeTLS(l, cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown(context.Background())
+
+	server.mu.Lock()
+	timeout := server.httpServer.ReadHeaderTimeout
+	server.mu.Unlock()
+	if timeout <= 0 {
+		t.Error("expected a read header timeout")
+	}
+
+	client := func() *http.Client {
+		return &http.Client{Timeout: time.Second, Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+	}
+	get := func(c *http.Client) error {
+		resp, err := c.Get("https://" + l.Addr().String() + "/")
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.Body.Close()
+	}
+
+	//the first client keeps its connection alive, which takes the only slot.
+	first := client()
+	if err := get(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(client()); err == nil {
+		t.Fatal("expected a second connection to be rejected")
+	}
+
+	first.CloseIdleConnections()
+	for i := 0; ; i++ {
+		if err := get(client()); err == nil {
+			break
+		}
+		if i == 50 {
+			t.Fatal("expected a new connection once the first one was closed")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestServeHTTPShutdownDrains(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inFlight := make(chan struct{})
+	server := NewServer(l.Addr().String())
+	server.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		time.Sleep(time.Millisecond * 100)
+		_, _ = w.Write([]byte("done"))
+	}))
+	served := make(chan error, 1)
+	go func() { served <- server.ServeTLS(l, cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	body := make(chan string, 1)
+	go func() {
+		resp, err := client.Get("https://" + l.Addr().String() + "/")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	<-inFlight
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if actual := <-body; actual != "done" {
+		t.Errorf("expected the in-flight request to finish; actual %q", actual)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("expected ServeTLS to return nil; actual %v", err)
+	}
+}
+
+func TestHijackServeHTTP(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const idle = time.Millisecond * 200
+	server := NewServer(l.Addr().String())
+	server.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = server.hijack(w, idle, func(conn net.Conn) error {
+			if _, err := conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n\r\n")); err != nil {
+				return err
+			}
+			_, err := io.Copy(conn, conn)
+			return err
+		})
+	}))
+	served := make(chan error, 1)
+	go func() { served <- server.ServeTLS(l, cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	//hijacking needs HTTP/1.1.
+	conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"http/1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	//busy for longer than idle, every exchange pushes the deadline back.
+	for i := range 3 {
+		time.Sleep(idle * 3 / 5)
+		if _, err := conn.Write([]byte("ping\n")); err != nil {
+			t.Fatal(err)
+		}
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("%d: expected the hijacked conn to stay open; actual %v", i, err)
+		}
+		if line != "ping\n" {
+			t.Errorf("%d: expected %q; actual %q", i, "ping\n", line)
+		}
+	}
+
+	//Shutdown waits for the hijacked conn, then closes it.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*30)
+	defer cancel()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Error("expected the hijacked conn to be closed")
+	}
+	if err := <-served; err != nil {
+		t.Errorf("expected ServeTLS to return nil; actual %v", err)
+	}
+}
+
+// closeErrListener fails to close, like a listener that was already closed under its server.
+type closeErrListener struct {
+	net.Listener
+}
+
+func (l closeErrListener) Close() error {
+	_ = l.Listener.Close()
+	return errors.New("close failed")
+}
+
+func TestShutdownDrainError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redirect := &http.Server{}
+	go func() { _ = redirect.Serve(closeErrListener{l}) }()
+	time.Sleep(time.Millisecond * 20)
+
+	server := NewServer("127.0.0.1:0")
+	server.redirect = redirect
+
+	//the drain failed long before ctx is done, that's not a success.
+	if err := server.Shutdown(context.Background()); err == nil || !strings.Contains(err.Error(), "close failed") {
+		t.Errorf("expected the drain error; actual %v", err)
+	}
+}
+
+func TestServeHTTPKeepsTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := dir+"/cert.pem", dir+"/key.pem"
+	if err := generatingCertificate([]string{"127.0.0.1"}, cert, key); err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server := NewServer(l.Addr().String(), WithTLSConfig(conf))
+	server.ServeHTTP(http.NotFoundHandler())
+	served := make(chan error, 1)
+	go func() { served <- server.ServeTLS(l, cert, key) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	//ALPN is offered on a copy, the caller's config is left alone.
+	if len(conf.NextProtos) != 0 {
+		t.Errorf("expected the caller's config to be unchanged; actual NextProtos %v", conf.NextProtos)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("expected ServeTLS to return nil; actual %v", err)
+	}
+}
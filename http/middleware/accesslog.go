@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RequestIDHeader carries the ID access logs record for a request.
+const RequestIDHeader = "X-Request-ID"
+
+// Format is the layout of the lines written by LogFormat.
+type Format int
+
+const (
+	// Common is the Common Log Format: host ident user [time] "request" status bytes.
+	Common Format = iota
+	// Combined is Common followed by the quoted referer and user agent.
+	Combined
+)
+
+// entry is what an access log records about a request once it's served.
+type entry struct {
+	r        *http.Request
+	start    time.Time
+	duration time.Duration
+	status   int
+	bytes    int64
+	id       string
+}
+
+// record serves r with next and returns what happened.
+func record(next http.Handler, w http.ResponseWriter, r *http.Request) entry {
+	rec := &responseRecorder{ResponseWriter: w}
+	start := time.Now()
+	next.ServeHTTP(rec, r)
+
+	//a handler that never wrote anything answered 200.
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	//the ID might have been made up by a handler further down.
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" {
+		id = w.Header().Get(RequestIDHeader)
+	}
+
+	return entry{r: r, start: start, duration: time.Since(start), status: status, bytes: rec.bytes, id: id}
+}
+
+// AccessLog logs every request to logger with its method, path, status, bytes written,
+// duration, remote address and request ID, at the error level for 5xx and info otherwise.
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			e := record(next, w, r)
+
+			level := slog.LevelInfo
+			if e.status >= 500 {
+				level = slog.LevelError
+			}
+
+			logger.LogAttrs(r.Context(), level, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", e.status),
+				slog.Int64("bytes", e.bytes),
+				slog.Duration("duration", e.duration),
+				slog.String("remote", r.RemoteAddr),
+				slog.String("request_id", e.id),
+			)
+		})
+	}
+}
+
+// LogFormat writes a line per request to w in the Common or Combined Log Format, the format
+// most log analyzers read. Lines are written whole, w can be shared by concurrent requests.
+func LogFormat(w io.Writer, f Format) Middleware {
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			line := formatLine(record(next, rw, r), f)
+
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = io.WriteString(w, line)
+		})
+	}
+}
+
+func formatLine(e entry, f Format) string {
+	host := e.r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	user := "-"
+	if u, _, ok := e.r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+
+	size := "-"
+	if e.bytes > 0 {
+		size = strconv.FormatInt(e.bytes, 10)
+	}
+
+	line := fmt.Sprintf("%s - %s [%s] %q %d %s",
+		host, user, e.start.Format("02/Jan/2006:15:04:05 -0700"),
+		e.r.Method+" "+e.r.URL.RequestURI()+" "+e.r.Proto, e.status, size)
+
+	if f == Combined {
+		line += fmt.Sprintf(" %q %q", orDash(e.r.Referer()), orDash(e.r.UserAgent()))
+	}
+
+	return line + "\n"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// responseRecorder remembers the status and the number of bytes written through it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	//informational responses are followed by the real one.
+	if r.status == 0 && status >= 200 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack keeps handlers that take over the connection working behind the access log, the
+// request is then logged as 101 unless the handler wrote a status first.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
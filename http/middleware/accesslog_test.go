@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	h := AccessLog(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/pot?x=1", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set(RequestIDHeader, "abc")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"method":     "GET",
+		"path":       "/pot",
+		"status":     float64(http.StatusTeapot),
+		"bytes":      float64(len("short and stout")),
+		"remote":     "192.0.2.1:1234",
+		"request_id": "abc",
+	}
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("%s: expected %v; actual %v", k, v, record[k])
+		}
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Error("expected the duration to be logged")
+	}
+}
+
+func TestLogFormat(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}
+
+	testCases := []struct {
+		format   Format
+		expected string
+	}{
+		{format: Common, expected: `^192\.0\.2\.1 - alice \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /a\?b=c HTTP/1\.1" 200 5\n$`},
+		{format: Combined, expected: `^192\.0\.2\.1 - alice \[.+\] "GET /a\?b=c HTTP/1\.1" 200 5 "http://ref/" "curl/8\.0"\n$`},
+	}
+
+	for _, c := range testCases {
+		var buf bytes.Buffer
+		r := httptest.NewRequest(http.MethodGet, "/a?b=c", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.SetBasicAuth("alice", "secret")
+		r.Header.Set("Referer", "http://ref/")
+		r.Header.Set("User-Agent", "curl/8.0")
+
+		LogFormat(&buf, c.format)(http.HandlerFunc(h)).ServeHTTP(httptest.NewRecorder(), r)
+
+		if !regexp.MustCompile(c.expected).MatchString(buf.String()) {
+			t.Errorf("format %d: expected to match %s; actual %q", c.format, c.expected, buf.String())
+		}
+	}
+}
+
+func TestAccessLogHijack(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	srv := httptest.NewServer(AccessLog(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 204 No Content\r\n\r\n")
+		_ = rw.Flush()
+	})))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected the hijacked connection to answer %d; actual %d", http.StatusNoContent, resp.StatusCode)
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+)
+
+// Middleware wraps a handler with behavior that runs around it.
+type Middleware func(http.Handler) http.Handler
+
+// Apply wraps h with mids, the first one being the outermost, nil entries are skipped.
+func Apply(h http.Handler, mids ...Middleware) http.Handler {
+	for i := len(mids) - 1; i >= 0; i-- {
+		m := mids[i]
+		if m != nil {
+			h = m(h)
+		}
+	}
+	return h
+}
+
+// DrainAndClose reads whatever is left of the request body once the handler is done and
+// closes it, so the connection can be reused for the next request.
+func DrainAndClose(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//call next handler
+		next.ServeHTTP(w, r)
+
+		//drain body
+		_, _ = io.Copy(io.Discard, r.Body)
+		_ = r.Body.Close()
+	})
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Apply(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), mark("outer"), nil, mark("inner"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if actual := strings.Join(order, ","); actual != "outer,inner,handler" {
+		t.Errorf("expected %q; actual %q", "outer,inner,handler", actual)
+	}
+}
+
+func TestDrainAndClose(t *testing.T) {
+	body := strings.NewReader("left unread by the handler")
+	h := DrainAndClose(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", body))
+
+	if body.Len() != 0 {
+		t.Errorf("expected the body to be drained; %d bytes left", body.Len())
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithContext shuts the server down once ctx is done when it was started with ListenAndServeTLS.
+func WithContext(ctx context.Context) Option {
+	return func(s *Server) {
+		s.ctx = ctx
+	}
+}
+
+// WithIdleTimeout closes the echo connections that stay idle for d, by default they never time out.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.maxIdle = d
+	}
+}
+
+// WithTLSConfig replaces the default TLS config, P-256 and TLS 1.2 or later. The certificate
+// files are only loaded when conf provides no certificate.
+func WithTLSConfig(conf *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = conf
+	}
+}
+
+// WithReady makes the server close ready the first time it accepts connections, for callers
+// that would rather select on a channel than call WaitReady.
+func WithReady(ready chan struct{}) Option {
+	return func(s *Server) {
+		s.ready = ready
+	}
+}
+
+// WithLogger sets the logger the server reports abnormal events to, slog.Default() by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithMaxConns caps the connections handled at once, 0 means unlimited.
+func WithMaxConns(n int) Option {
+	return func(s *Server) {
+		s.maxConns = n
+	}
+}
+
+// WithMaxConnsPerIP caps the connections handled at once for each remote IP, 0 means unlimited.
+func WithMaxConnsPerIP(n int) Option {
+	return func(s *Server) {
+		s.maxConnsPerIP = n
+	}
+}
+
+// WithExcessPolicy picks what happens to the connections over the limits, RejectExcess by default.
+func WithExcessPolicy(p ExcessPolicy) Option {
+	return func(s *Server) {
+		s.excess = p
+	}
+}
+
+// WithAcceptBackoff sets how long the server waits before accepting again after a temporary
+// error, doubling from initial up to limit, 5ms and 1s by default.
+func WithAcceptBackoff(initial, limit time.Duration) Option {
+	return func(s *Server) {
+		s.backoff = acceptBackoff{min: initial, max: limit}
+	}
+}
+
+// OnAcceptError registers fn to be called with every Accept error, temporary or not.
+func OnAcceptError(fn func(err error)) Option {
+	return func(s *Server) {
+		s.onAcceptError = fn
+	}
+}
+
+// WithHTTPRedirect makes ListenAndServeTLS also bind addr in cleartext and redirect every
+// request there to the TLS address with a 301.
+func WithHTTPRedirect(addr string) Option {
+	return func(s *Server) {
+		s.redirectAddr = addr
+	}
+}
+
+// WithACMEHandler wraps the redirect handler, so ACME HTTP-01 challenges can be answered on the
+// cleartext port, autocert.Manager.HTTPHandler has the right signature.
+func WithACMEHandler(wrap func(fallback http.Handler) http.Handler) Option {
+	return func(s *Server) {
+		s.acme = wrap
+	}
+}
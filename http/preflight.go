@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Preflight checks that the server is able to start before it does, and fails fast with an
+// actionable error instead of whatever the first failing syscall happens to say:
+//   - the address can be bound, and if it's in use, which process holds it (Linux only).
+//   - the certificate and key files exist, belong together and the certificate isn't expired.
+//
+// The key pair is not checked when the TLS config already provides certificates.
+func (s *Server) Preflight(cert, key string) error {
+	l, err := listen(context.Background(), s.Family, s.addr)
+	if err != nil {
+		return listenError(s.addr, err)
+	}
+	_ = l.Close()
+
+	if s.tlsConfig != nil && (len(s.tlsConfig.Certificates) > 0 || s.tlsConfig.GetCertificate != nil) {
+		return nil
+	}
+
+	return checkKeyPair(cert, key)
+}
+
+// listenError explains why binding addr failed.
+func listenError(addr string, err error) error {
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		_, portStr, splitErr := net.SplitHostPort(addr)
+		port, convErr := strconv.Atoi(portStr)
+		if splitErr != nil || convErr != nil {
+			return fmt.Errorf("binding tcp %s: address already in use: %w", addr, err)
+		}
+
+		pid, name, ok := portOwner(port)
+		if !ok {
+			return fmt.Errorf("binding tcp %s: address already in use by another process (stop it or pick another port): %w", addr, err)
+		}
+		return fmt.Errorf("binding tcp %s: address already in use by pid %d (%s): %w", addr, pid, name, err)
+
+	case errors.Is(err, syscall.EACCES):
+		return fmt.Errorf("binding tcp %s: permission denied, ports below 1024 need root or CAP_NET_BIND_SERVICE: %w", addr, err)
+
+	default:
+		return fmt.Errorf("binding tcp %s: %w", addr, err)
+	}
+}
+
+// checkKeyPair verifies the certificate and key files can be loaded, match each other and
+// that the certificate is currently valid.
+func checkKeyPair(cert, key string) error {
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return fmt.Errorf("loading key pair %s and %s (missing files, or key does not belong to the certificate?): %w", cert, key, err)
+	}
+
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("parsing certificate %s: %w", cert, err)
+	}
+
+	now := time.Now()
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("certificate %s expired at %s", cert, leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("certificate %s is not valid before %s", cert, leaf.NotBefore.Format(time.RFC3339))
+	}
+
+	return nil
+}
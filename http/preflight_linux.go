@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// portOwner finds the process listening on the tcp port by matching the socket inode from
+// /proc/net/tcp{,6} against the fds in /proc/<pid>/fd. Processes of other users are only
+// visible with enough privileges.
+func portOwner(port int) (int, string, bool) {
+	inodes := make(map[string]struct{})
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		listeningInodes(table, port, inodes)
+	}
+
+	if len(inodes) == 0 {
+		return 0, "", false
+	}
+
+	pids, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return 0, "", false
+	}
+
+	for _, dir := range pids {
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+			if _, ok := inodes[inode]; !ok {
+				continue
+			}
+
+			pid, err := strconv.Atoi(filepath.Base(dir))
+			if err != nil {
+				continue
+			}
+
+			comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+			return pid, strings.TrimSpace(string(comm)), true
+		}
+	}
+
+	return 0, "", false
+}
+
+// listeningInodes adds the inodes of the sockets listening on port in table to inodes.
+func listeningInodes(table string, port int, inodes map[string]struct{}) {
+	f, err := os.Open(table)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	//local_address is hex ip:port, 0A is the LISTEN state.
+	suffix := fmt.Sprintf(":%04X", port)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() //header
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		if strings.HasSuffix(fields[1], suffix) && fields[3] == "0A" {
+			inodes[fields[9]] = struct{}{}
+		}
+	}
+}
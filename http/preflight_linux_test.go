@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestPreflightAddressInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+
+	server := NewServer(l.Addr().String())
+
+	err = server.Preflight("cert.pem", "key.pem")
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Fatalf("expected %v; actual %v", syscall.EADDRINUSE, err)
+	}
+
+	//we are the ones holding the port.
+	if pid := os.Getpid(); !strings.Contains(err.Error(), "pid "+strconv.Itoa(pid)) {
+		t.Fatalf("expected the error to name pid %d; actual %q", pid, err)
+	}
+}
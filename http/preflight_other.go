@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// portOwner is only implemented on Linux.
+func portOwner(port int) (int, string, bool) {
+	return 0, "", false
+}
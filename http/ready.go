@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// errServerClosed is what WaitReady returns when ServeTLS is called after Shutdown.
+var errServerClosed = errors.New("server closed")
+
+// WaitReady blocks until the server accepts connections, or fails to start, in which case the
+// error that stopped it is returned, or until ctx is done. The outcome sticks until the next
+// ListenAndServeTLS or ServeTLS call starts over, or until ResetReady.
+func (s *Server) WaitReady(ctx context.Context) error {
+	s.mu.Lock()
+	ch := s.readiness()
+	s.mu.Unlock()
+
+	select {
+	case <-ch:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.readyErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ResetReady makes WaitReady block again until the next start settles it. Call it before
+// restarting the server on another goroutine, so WaitReady can't return the previous outcome
+// before the new call got going.
+func (s *Server) ResetReady() {
+	s.starting()
+}
+
+// starting opens a new round of readiness unless one is already waiting to be settled.
+func (s *Server) starting() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readySettled {
+		s.readyCh = make(chan struct{})
+		s.readySettled = false
+		s.readyErr = nil
+	}
+}
+
+// started settles the current round with err, nil meaning the server accepts connections.
+// Only the first call of a round counts.
+func (s *Server) started(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readySettled {
+		return
+	}
+
+	ch := s.readiness()
+	s.readyErr = err
+	s.readySettled = true
+	close(ch)
+
+	//the channel given to WithReady is only closed once, the first time the server is up.
+	if err == nil && s.ready != nil {
+		close(s.ready)
+		s.ready = nil
+	}
+}
+
+// readiness returns the channel of the current round, it must be called with mu held.
+func (s *Server) readiness() chan struct{} {
+	if s.readyCh == nil {
+		s.readyCh = make(chan struct{})
+	}
+	return s.readyCh
+}
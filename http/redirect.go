@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// redirectHandler answers every cleartext request with a 301 to the same host and URI over
+// https, on the port of tlsAddr unless it's the default one.
+func redirectHandler(tlsAddr string) http.Handler {
+	_, port, err := net.SplitHostPort(tlsAddr)
+	if err != nil || port == "443" {
+		port = ""
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" {
+			host = net.JoinHostPort(host, port)
+		} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			//a bare IPv6 address still needs its brackets in a URL.
+			host = "[" + host + "]"
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// serveRedirect binds the cleartext address set by WithHTTPRedirect, if any, and serves the
+// redirects on it until Shutdown or stopRedirect.
+func (s *Server) serveRedirect() error {
+	if s.redirectAddr == "" {
+		return nil
+	}
+
+	l, err := listen(context.Background(), s.Family, s.redirectAddr)
+	if err != nil {
+		return listenError(s.redirectAddr, err)
+	}
+
+	var handler http.Handler = redirectHandler(s.addr)
+	if s.acme != nil {
+		handler = s.acme(handler)
+	}
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: defaultShutdownTimeout}
+	s.mu.Lock()
+	s.redirect = srv
+	s.mu.Unlock()
+
+	go func() {
+		if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("serving redirects", "addr", s.redirectAddr, "err", fmt.Errorf("serve: %w", err))
+		}
+	}()
+
+	return nil
+}
+
+// stopRedirect closes the redirect listener right away, once the TLS server is gone.
+func (s *Server) stopRedirect() {
+	s.mu.Lock()
+	srv := s.redirect
+	s.redirect = nil
+	s.mu.Unlock()
+
+	if srv != nil {
+		_ = srv.Close()
+	}
+}
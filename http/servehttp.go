@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ServeHTTP switches the server from raw connections to HTTP: the next ListenAndServeTLS or
+// ServeTLS hands its TLS listener to an http.Server serving handler, over HTTP/2 when the client
+// offers it, and /version reports the build info. The connections go through the same accept
+// loop as raw ones, so the server's ctx, idle timeout, readiness, connection limits, accept
+// backoff and Shutdown all apply, request contexts are cancelled by Shutdown.
+//
+// Calling it again swaps handler in between requests, without touching the listener.
+func (s *Server) ServeHTTP(handler http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.httpHandler == nil {
+		s.httpHandler = newSwapHandler(handler)
+		return
+	}
+	s.httpHandler.Swap(handler)
+}
+
+// httpMode returns the handler set by ServeHTTP, nil while serving raw connections.
+func (s *Server) httpMode() http.Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.httpHandler == nil {
+		return nil
+	}
+	return s.httpHandler
+}
+
+// withALPN offers HTTP/2 and HTTP/1.1 during the handshake unless the config already picks protocols.
+// The config may be the caller's own, see WithTLSConfig, so it's cloned rather than changed.
+func (s *Server) withALPN() {
+	if len(s.tlsConfig.NextProtos) > 0 {
+		return
+	}
+	s.tlsConfig = s.tlsConfig.Clone()
+	s.tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+}
+
+// defaultReadHeaderTimeout bounds how long a client may take to send the request headers.
+const defaultReadHeaderTimeout = 10 * time.Second
+
+// serveHTTP starts the http.Server serving handler and returns the connection handler feeding it,
+// so HTTP connections go through the same accept loop, limits and backoff as raw ones. ctx is the
+// one Shutdown cancels, stop closes the http.Server unless Shutdown is draining it.
+func (s *Server) serveHTTP(ctx context.Context, handler http.Handler) (connHandler, func()) {
+	srv := &http.Server{
+		Handler:           s.withVersion(handler),
+		TLSConfig:         s.tlsConfig,
+		IdleTimeout:       s.maxIdle,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelWarn),
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	route := newHTTPRoute(srv)
+
+	s.mu.Lock()
+	closed := s.closed
+	if !closed {
+		s.httpServer = srv
+		s.httpRoute = route
+	}
+	s.mu.Unlock()
+
+	//Shutdown came first and won't know about srv.
+	if closed {
+		_ = srv.Close()
+	}
+
+	serve := func(_ context.Context, conn net.Conn) {
+		//the http.Server owns the conn until it closes it, Shutdown drains it through srv.
+		route.Serve(context.Background(), conn)
+	}
+	stop := func() {
+		if !s.shuttingDown() {
+			_ = srv.Close()
+		}
+	}
+	return serve, stop
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultShutdownTimeout bounds the drain when the server's ctx is cancelled.
+const defaultShutdownTimeout = 5 * time.Second
+
+// Shutdown stops accepting connections, tells the handlers to finish through their ctx and
+// waits for them. Once ctx is done the remaining connections are closed under the handlers and
+// ctx.Err() is returned. An http.Server that fails to drain before that gets its connections
+// closed the same way, and its error is returned. ServeTLS returns nil after Shutdown, and so
+// does any later call to it.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	if s.stop != nil {
+		s.stop()
+	}
+	redirect, httpServer := s.redirect, s.httpServer
+	s.mu.Unlock()
+
+	//set when an http.Server gave up draining, before done is closed.
+	var drainErr error
+	done := make(chan struct{})
+	go func() {
+		for _, srv := range []*http.Server{redirect, httpServer} {
+			if srv != nil {
+				drainErr = errors.Join(drainErr, srv.Shutdown(ctx))
+			}
+		}
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		if drainErr == nil {
+			return nil
+		}
+	case <-ctx.Done():
+	}
+
+	//force close the stragglers, skipping the TLS close_notify a stuck peer would never read.
+	s.mu.Lock()
+	s.logger.Warn("shutdown deadline reached, closing connections", "count", len(s.conns))
+	if s.httpServer != nil {
+		_ = s.httpServer.Close()
+	}
+	for conn := range s.conns {
+		if tc, ok := conn.(*tls.Conn); ok {
+			_ = tc.NetConn().Close()
+			continue
+		}
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	<-done
+	//drainErr is usually ctx.Err() itself when ctx is what cut the drain short.
+	if err := ctx.Err(); err != nil && !errors.Is(drainErr, err) {
+		return errors.Join(drainErr, err)
+	}
+	return drainErr
+}
+
+// serving registers l as the server's listener and returns the ctx handed to its handlers,
+// it reports false once Shutdown has been called.
+func (s *Server) serving(l net.Listener) (context.Context, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.listener = l
+	s.stop = cancel
+	return ctx, true
+}
+
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// track adds conn to the connections Shutdown waits for, unless it's already shutting down.
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+// trackHijacked makes Shutdown wait for a hijacked conn until the returned func is called, it
+// reports false once Shutdown has been called. A conn the server accepted itself is tracked by
+// the accept loop already, the returned func then hands it back to the httpRoute serving it.
+func (s *Server) trackHijacked(conn net.Conn) (func(), bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.conns[conn]; ok {
+		route := s.httpRoute
+		return func() {
+			if route != nil {
+				route.release(conn)
+			}
+		}, true
+	}
+
+	if s.closed {
+		return nil, false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return func() { s.untrack(conn) }, true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.wg.Done()
+}
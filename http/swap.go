@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// swapHandler serves every request with the handler it currently holds. The whole route tree
+// and middleware stack can be rebuilt from a new config and swapped in while the server keeps
+// its listener: requests already running finish on the old tree, the next ones get the new one.
+//
+// Reading the handler is a single atomic load, there's no lock on the request path.
+type swapHandler struct {
+	h atomic.Pointer[http.Handler]
+}
+
+func newSwapHandler(h http.Handler) *swapHandler {
+	s := &swapHandler{}
+	s.Swap(h)
+	return s
+}
+
+// Swap installs h for the next requests and returns the previous handler, nil means 404.
+func (s *swapHandler) Swap(h http.Handler) http.Handler {
+	if h == nil {
+		h = http.NotFoundHandler()
+	}
+
+	old := s.h.Swap(&h)
+	if old == nil {
+		return nil
+	}
+	return *old
+}
+
+func (s *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.h.Load()).ServeHTTP(w, r)
+}
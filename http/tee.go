@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"networking/concurrency-patterns/workerpool"
+)
+
+// teedRequest is what a body processor gets, a copy detached from the request so it can outlive it.
+type teedRequest struct {
+	Method     string
+	URL        string
+	RemoteAddr string
+	Header     http.Header
+	Body       []byte
+	//the handler read more than the limit, Body only holds the first bytes.
+	Truncated bool
+}
+
+// bodyProcessor inspects a request once it has been served: audit, analytics, WAF-style rules.
+type bodyProcessor func(ctx context.Context, r teedRequest)
+
+/*
+bodyTee hands a copy of every request body to async processors without adding latency to the
+response. The body isn't read up front, the copy fills up as the handler reads it, and it's only
+submitted to the pool once the handler returned. Bytes the handler never read are not seen by the
+processors either.
+
+When the processors fall behind the pool's policy applies, Reject or DropOldest keep the response
+path free, Block makes it wait for room. Rejected submissions are counted.
+*/
+type bodyTee struct {
+	pool       *workerpool.Pool
+	limit      int64
+	processors []bodyProcessor
+	rejected   atomic.Int64
+}
+
+func newBodyTee(pool *workerpool.Pool, limit int64, processors ...bodyProcessor) *bodyTee {
+	return &bodyTee{pool: pool, limit: limit, processors: processors}
+}
+
+// Rejected returns the number of processor runs the pool turned down.
+func (t *bodyTee) Rejected() int64 {
+	return t.rejected.Load()
+}
+
+func (t *bodyTee) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(t.processors) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body := &limitedCopy{limit: t.limit}
+		if r.Body != nil {
+			r.Body = readCloser{Reader: io.TeeReader(r.Body, body), Closer: r.Body}
+		}
+
+		next.ServeHTTP(w, r)
+
+		req := teedRequest{
+			Method:     r.Method,
+			URL:        r.URL.String(),
+			RemoteAddr: r.RemoteAddr,
+			Header:     r.Header.Clone(),
+			Body:       body.buf.Bytes(),
+			Truncated:  body.truncated,
+		}
+
+		for _, p := range t.processors {
+			err := t.pool.Submit(r.Context(), func(ctx context.Context) {
+				p(ctx, req)
+			})
+			if err != nil {
+				t.rejected.Add(1)
+			}
+		}
+	})
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedCopy keeps the first limit bytes written to it and discards the rest.
+type limitedCopy struct {
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (c *limitedCopy) Write(p []byte) (int, error) {
+	room := c.limit - int64(c.buf.Len())
+	if int64(len(p)) > room {
+		c.truncated = true
+		_, _ = c.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+
+	return c.buf.Write(p)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"slices"
+)
+
+// buildVersion is the body of the /version endpoint.
+type buildVersion struct {
+	Module     string   `json:"module"`
+	Version    string   `json:"version"`
+	Revision   string   `json:"revision,omitempty"`
+	Modified   bool     `json:"modified,omitempty"`
+	Go         string   `json:"go"`
+	Subsystems []string `json:"subsystems"`
+	Protocols  []string `json:"protocols"`
+}
+
+// readVersion collects the build info embedded by the go tool, tests and binaries built without
+// module support only get the Go version.
+func readVersion(subsystems []string, protocols []string) buildVersion {
+	v := buildVersion{
+		Go:         runtime.Version(),
+		Subsystems: subsystems,
+		Protocols:  protocols,
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+
+	v.Module = info.Main.Path
+	v.Version = info.Main.Version
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			v.Revision = s.Value
+		case "vcs.modified":
+			v.Modified = s.Value == "true"
+		}
+	}
+
+	return v
+}
+
+// versionHandler serves the build info, the enabled subsystems and the supported protocols as
+// JSON, so every server built from this package can be inventoried the same way. ServeHTTP mode
+// mounts it at /version, see withVersion.
+func versionHandler(subsystems []string, protocols []string) http.Handler {
+	//build info doesn't change while the process runs.
+	body, err := json.Marshal(readVersion(subsystems, protocols))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
+
+// withVersion answers /version with the build info of s and hands every other request to next.
+// It's called once the TLS config is final, protocols are the ones offered during the handshake.
+func (s *Server) withVersion(next http.Handler) http.Handler {
+	version := versionHandler(s.subsystems(), slices.Clone(s.tlsConfig.NextProtos))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			version.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// subsystems lists the optional parts of s that are enabled.
+func (s *Server) subsystems() []string {
+	subsystems := []string{"tls"}
+	if s.tlsConfig.ClientAuth != tls.NoClientCert {
+		subsystems = append(subsystems, "mtls")
+	}
+	if s.maxConns > 0 || s.maxConnsPerIP > 0 {
+		subsystems = append(subsystems, "limits")
+	}
+	if s.redirectAddr != "" {
+		subsystems = append(subsystems, "redirect")
+	}
+	return subsystems
+}
@@ -0,0 +1,197 @@
+package netmon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Kind is the kind of change an Event reports.
+type Kind int
+
+const (
+	// InterfaceAdded is reported for an interface that appeared.
+	InterfaceAdded Kind = iota
+	// InterfaceRemoved is reported for an interface that disappeared.
+	InterfaceRemoved
+	// LinkUp is reported when an interface goes up.
+	LinkUp
+	// LinkDown is reported when an interface goes down.
+	LinkDown
+	// AddrAdded is reported for an address assigned to an interface.
+	AddrAdded
+	// AddrRemoved is reported for an address removed from an interface.
+	AddrRemoved
+)
+
+func (k Kind) String() string {
+	switch k {
+	case InterfaceAdded:
+		return "interface added"
+	case InterfaceRemoved:
+		return "interface removed"
+	case LinkUp:
+		return "link up"
+	case LinkDown:
+		return "link down"
+	case AddrAdded:
+		return "address added"
+	case AddrRemoved:
+		return "address removed"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Event is a single change, Addr is only set for AddrAdded and AddrRemoved.
+type Event struct {
+	Kind      Kind
+	Interface string
+	Addr      string
+}
+
+// Interface is the state of one network interface.
+type Interface struct {
+	Name  string
+	Index int
+	Up    bool
+	//in CIDR notation, sorted.
+	Addrs []string
+}
+
+// State is every interface of the host, by name.
+type State map[string]Interface
+
+// Current reads the interfaces and their addresses from the OS.
+func Current() (State, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("interfaces: %w", err)
+	}
+
+	state := make(State, len(ifaces))
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("addrs %s: %w", iface.Name, err)
+		}
+
+		i := Interface{
+			Name:  iface.Name,
+			Index: iface.Index,
+			Up:    iface.Flags&net.FlagUp != 0,
+			Addrs: make([]string, 0, len(addrs)),
+		}
+		for _, a := range addrs {
+			i.Addrs = append(i.Addrs, a.String())
+		}
+		sort.Strings(i.Addrs)
+		state[iface.Name] = i
+	}
+
+	return state, nil
+}
+
+// Diff returns the events that lead from old to new, ordered by interface name.
+func Diff(old, new State) []Event {
+	names := make([]string, 0, len(old)+len(new))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var events []Event
+	for _, name := range names {
+		o, hadOld := old[name]
+		n, hasNew := new[name]
+
+		switch {
+		case !hadOld:
+			events = append(events, Event{Kind: InterfaceAdded, Interface: name})
+		case !hasNew:
+			events = append(events, Event{Kind: InterfaceRemoved, Interface: name})
+		}
+
+		if o.Up != n.Up {
+			kind := LinkDown
+			if n.Up {
+				kind = LinkUp
+			}
+			events = append(events, Event{Kind: kind, Interface: name})
+		}
+
+		for _, a := range o.Addrs {
+			if !slices.Contains(n.Addrs, a) {
+				events = append(events, Event{Kind: AddrRemoved, Interface: name, Addr: a})
+			}
+		}
+		for _, a := range n.Addrs {
+			if !slices.Contains(o.Addrs, a) {
+				events = append(events, Event{Kind: AddrAdded, Interface: name, Addr: a})
+			}
+		}
+	}
+
+	return events
+}
+
+/*
+Monitor polls the interfaces of the host and reports every change as an Event, so a server can
+rebind its multicast groups or update the addresses it advertises without a restart.
+
+It polls rather than subscribing to netlink, which keeps it portable, at the cost of noticing a
+change up to one interval late.
+*/
+type Monitor struct {
+	interval time.Duration
+	//reads the state, replaced in tests.
+	current func() (State, error)
+}
+
+// defaultInterval is the polling interval of a Monitor created without a positive one.
+const defaultInterval = 5 * time.Second
+
+// New creates a Monitor polling every interval, defaultInterval when it isn't positive.
+func New(interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Monitor{interval: interval, current: Current}
+}
+
+// Run calls fn with every change until ctx is done, the state when Run starts is the baseline
+// and reports nothing. A failed poll is skipped, the next one diffs against the last good state.
+func (m *Monitor) Run(ctx context.Context, fn func(Event)) error {
+	last, err := m.current()
+	if err != nil {
+		return fmt.Errorf("baseline: %w", err)
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			state, err := m.current()
+			if err != nil {
+				continue
+			}
+
+			for _, e := range Diff(last, state) {
+				fn(e)
+			}
+			last = state
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
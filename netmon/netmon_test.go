@@ -0,0 +1,112 @@
+package netmon
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := State{
+		"eth0":  {Name: "eth0", Up: true, Addrs: []string{"10.0.0.2/24", "fe80::1/64"}},
+		"wlan0": {Name: "wlan0", Up: true},
+	}
+	new := State{
+		"eth0": {Name: "eth0", Up: false, Addrs: []string{"10.0.0.3/24", "fe80::1/64"}},
+		"wg0":  {Name: "wg0", Up: true, Addrs: []string{"10.8.0.1/32"}},
+	}
+
+	expected := []Event{
+		{Kind: LinkDown, Interface: "eth0"},
+		{Kind: AddrRemoved, Interface: "eth0", Addr: "10.0.0.2/24"},
+		{Kind: AddrAdded, Interface: "eth0", Addr: "10.0.0.3/24"},
+		{Kind: InterfaceAdded, Interface: "wg0"},
+		{Kind: LinkUp, Interface: "wg0"},
+		{Kind: AddrAdded, Interface: "wg0", Addr: "10.8.0.1/32"},
+		{Kind: InterfaceRemoved, Interface: "wlan0"},
+		{Kind: LinkDown, Interface: "wlan0"},
+	}
+
+	if actual := Diff(old, new); !slices.Equal(actual, expected) {
+		t.Errorf("expected %v; actual %v", expected, actual)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	states := []State{
+		{"eth0": {Name: "eth0", Up: true}},
+		//a failed poll in between must not report anything.
+		nil,
+		{"eth0": {Name: "eth0", Up: false}},
+	}
+
+	var mu sync.Mutex
+	polls := 0
+	m := New(time.Millisecond)
+	m.current = func() (State, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		s := states[min(polls, len(states)-1)]
+		polls++
+		if s == nil {
+			return nil, errors.New("poll failed")
+		}
+		return s, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run(ctx, func(e Event) { events <- e })
+	}()
+
+	select {
+	case e := <-events:
+		if e != (Event{Kind: LinkDown, Interface: "eth0"}) {
+			t.Errorf("expected eth0 to go down; actual %v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an event")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v; actual %v", context.Canceled, err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected a single event; actual %d more", len(events))
+	}
+}
+
+func TestMonitorDefaultInterval(t *testing.T) {
+	//a non positive interval would make Run panic on its ticker.
+	for _, interval := range []time.Duration{0, -time.Second} {
+		m := New(interval)
+		m.current = func() (State, error) { return State{}, nil }
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+		err := m.Run(ctx, func(Event) {})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("interval %s: expected %v; actual %v", interval, context.DeadlineExceeded, err)
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	state, err := Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state["lo"]; !ok {
+		t.Skip("no loopback interface named lo")
+	}
+	if !state["lo"].Up {
+		t.Error("expected the loopback interface to be up")
+	}
+}
@@ -0,0 +1,128 @@
+package adaptivelimit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"networking/stablity-patterns/decorator"
+	"networking/stablity-patterns/telemetry"
+)
+
+// ErrLimitExceeded is returned when the current concurrency limit is already in use.
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+// decrease is the multiplicative factor applied to the limit on every overloaded call.
+const decrease = 0.9
+
+// Effector The function that interacts with the service
+type Effector func(ctx context.Context) (string, error)
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithLogger sets the Logger that receives the reject events, the default discards them.
+func WithLogger(l telemetry.Logger) Option {
+	return func(lim *Limiter) {
+		lim.logger = l
+	}
+}
+
+/*
+Limiter is a concurrency limiter whose ceiling adapts to the observed behaviour of the dependency
+using AIMD (additive increase, multiplicative decrease), the same algorithm TCP uses for its
+congestion window, instead of a hand-tuned bulkhead size.
+
+Every call that finishes fast and without error adds 1/limit to the limit, so the limit grows
+by about one each time a full limit's worth of calls succeed. Every call that fails or takes longer
+than the latency threshold multiplies the limit by 0.9, so capacity drops fast when the dependency
+shows signs of overload. The limit always stays within [min, max].
+*/
+type Limiter struct {
+	min              float64
+	max              float64
+	latencyThreshold time.Duration
+	logger           telemetry.Logger
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+}
+
+// NewLimiter creates a Limiter starting at initial concurrent calls, calls slower than
+// latencyThreshold count as overloaded. min is at least 1, a limit of zero would reject every
+// call and never see the successes it needs to grow back.
+func NewLimiter(initial, min, max int, latencyThreshold time.Duration, opts ...Option) *Limiter {
+	min = int(math.Max(1, float64(min)))
+	max = int(math.Max(float64(min), float64(max)))
+
+	l := &Limiter{
+		min:              float64(min),
+		max:              float64(max),
+		latencyThreshold: latencyThreshold,
+		logger:           telemetry.Nop(),
+		limit:            math.Max(float64(min), math.Min(float64(max), float64(initial))),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Limit returns the current concurrency ceiling.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Wrap puts the limiter in front of effector, calls over the current limit are rejected
+// with ErrLimitExceeded.
+func (l *Limiter) Wrap(effector Effector) Effector {
+	return func(ctx context.Context) (string, error) {
+		l.mu.Lock()
+		if l.inflight >= int(l.limit) {
+			limit := int(l.limit)
+			l.mu.Unlock()
+			l.logger.Log(ctx, telemetry.EventReject, slog.String("pattern", "adaptive-limit"), slog.Int("limit", limit))
+			return "", ErrLimitExceeded
+		}
+		l.inflight++
+		l.mu.Unlock()
+
+		start := time.Now()
+		response, err := effector(ctx)
+		l.observe(time.Since(start), err)
+
+		return response, err
+	}
+}
+
+func (l *Limiter) observe(latency time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+
+	//the caller giving up says nothing about the dependency.
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err != nil || latency > l.latencyThreshold {
+		l.limit = math.Max(l.min, l.limit*decrease)
+		return
+	}
+
+	l.limit = math.Min(l.max, l.limit+1/l.limit)
+}
+
+// Decorator returns Wrap as a decorator.Decorator, every effector it decorates shares this limiter.
+func (l *Limiter) Decorator() decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](l.Wrap(Effector(effector)))
+	}
+}
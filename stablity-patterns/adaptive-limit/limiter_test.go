@@ -0,0 +1,82 @@
+package adaptivelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterAdapts(t *testing.T) {
+	l := NewLimiter(4, 1, 8, time.Millisecond*20)
+
+	fast := l.Wrap(func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+
+	for range 100 {
+		if _, err := fast(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if actual := l.Limit(); actual != 8 {
+		t.Fatalf("expected limit to grow to the max 8; actual %d", actual)
+	}
+
+	failing := l.Wrap(func(ctx context.Context) (string, error) {
+		return "", errors.New("overloaded")
+	})
+
+	for range 50 {
+		_, _ = failing(context.Background())
+	}
+
+	if actual := l.Limit(); actual != 1 {
+		t.Fatalf("expected limit to shrink to the min 1; actual %d", actual)
+	}
+}
+
+func TestLimiterRejects(t *testing.T) {
+	l := NewLimiter(1, 1, 1, time.Second)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	slow := l.Wrap(func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		return "ok", nil
+	})
+
+	go func() { _, _ = slow(context.Background()) }()
+	<-started
+
+	if _, err := slow(context.Background()); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected %v; actual %v", ErrLimitExceeded, err)
+	}
+
+	close(release)
+}
+
+func TestLimiterMinClamped(t *testing.T) {
+	l := NewLimiter(2, 0, 4, time.Second)
+
+	failing := l.Wrap(func(ctx context.Context) (string, error) {
+		return "", errors.New("overloaded")
+	})
+	for range 50 {
+		_, _ = failing(context.Background())
+	}
+
+	//one call at a time still gets through, so the limit can recover.
+	fast := l.Wrap(func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	if _, err := fast(context.Background()); err != nil {
+		t.Fatalf("expected the limit to keep at least one slot; actual %v", err)
+	}
+	if actual := l.Limit(); actual != 2 {
+		t.Errorf("expected the limit to grow back to 2; actual %d", actual)
+	}
+}
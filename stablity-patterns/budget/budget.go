@@ -0,0 +1,69 @@
+package budget
+
+import (
+	"context"
+	"time"
+)
+
+// Share returns a child of ctx whose deadline is fraction of the time left until ctx's deadline.
+// Without a deadline on ctx there is no budget to share and the child only inherits cancellation.
+func Share(ctx context.Context, fraction float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	remaining := time.Until(deadline)
+	return context.WithTimeout(ctx, time.Duration(float64(remaining)*fraction))
+}
+
+// Reserve returns a child of ctx that ends early enough to leave fraction of the time left for
+// whatever comes after, e.g. Reserve(ctx, 0.2) keeps 20% for the final write.
+func Reserve(ctx context.Context, fraction float64) (context.Context, context.CancelFunc) {
+	return Share(ctx, 1-fraction)
+}
+
+/*
+Budget splits the deadline of a ctx across sequential downstream calls by weight, so stacking
+timeout, retry and breaker doesn't leave the last call with zero budget.
+
+Every call to Next hands out the next step's share of the time that is left at that moment,
+not of the original budget, so time saved by a fast step rolls over to the ones after it.
+*/
+type Budget struct {
+	ctx     context.Context
+	weights []float64
+	step    int
+}
+
+// Split creates a Budget with one step per weight, e.g. Split(ctx, 1, 1, 2) gives the last of
+// three calls half of the time.
+func Split(ctx context.Context, weights ...float64) *Budget {
+	return &Budget{
+		ctx:     ctx,
+		weights: weights,
+	}
+}
+
+// Next returns the ctx for the next step. Steps beyond the weights get whatever is left, and
+// when the weights left add up to zero the time left is split evenly between their steps.
+func (b *Budget) Next() (context.Context, context.CancelFunc) {
+	if b.step >= len(b.weights) {
+		return context.WithCancel(b.ctx)
+	}
+
+	left := b.weights[b.step:]
+	var total float64
+	for _, w := range left {
+		total += w
+	}
+
+	//no weight to go by, 0/0 would hand out an expired ctx.
+	share := 1 / float64(len(left))
+	if total > 0 {
+		share = b.weights[b.step] / total
+	}
+	b.step++
+
+	return Share(b.ctx, share)
+}
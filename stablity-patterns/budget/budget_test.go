@@ -0,0 +1,95 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func remaining(t *testing.T, ctx context.Context) time.Duration {
+	t.Helper()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("expected ctx to have a deadline")
+	}
+	return time.Until(deadline)
+}
+
+func within(actual, expected time.Duration) bool {
+	diff := actual - expected
+	return diff < time.Millisecond*50 && diff > -time.Millisecond*50
+}
+
+func TestReserve(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ctx, cancelChild := Reserve(parent, 0.2)
+	defer cancelChild()
+
+	if actual := remaining(t, ctx); !within(actual, time.Millisecond*800) {
+		t.Errorf("expected about 800ms; actual %s", actual)
+	}
+
+	//no deadline means nothing to reserve.
+	ctx, cancelChild = Reserve(context.Background(), 0.2)
+	defer cancelChild()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline")
+	}
+}
+
+func TestSplitRollsOver(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), time.Millisecond*900)
+	defer cancel()
+
+	b := Split(parent, 1, 1, 1)
+
+	first, cancelFirst := b.Next()
+	defer cancelFirst()
+
+	if actual := remaining(t, first); !within(actual, time.Millisecond*300) {
+		t.Errorf("expected about 300ms; actual %s", actual)
+	}
+
+	//the first step finished right away, so the second one gets half of what's left.
+	second, cancelSecond := b.Next()
+	defer cancelSecond()
+
+	if actual := remaining(t, second); !within(actual, time.Millisecond*450) {
+		t.Errorf("expected about 450ms; actual %s", actual)
+	}
+
+	third, cancelThird := b.Next()
+	defer cancelThird()
+
+	if actual := remaining(t, third); !within(actual, time.Millisecond*900) {
+		t.Errorf("expected about 900ms; actual %s", actual)
+	}
+}
+
+func TestSplitZeroWeights(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), time.Millisecond*900)
+	defer cancel()
+
+	b := Split(parent, 0, 0)
+
+	first, cancelFirst := b.Next()
+	defer cancelFirst()
+
+	if actual := remaining(t, first); !within(actual, time.Millisecond*450) {
+		t.Errorf("expected about 450ms; actual %s", actual)
+	}
+	if first.Err() != nil {
+		t.Errorf("expected a live ctx; actual %v", first.Err())
+	}
+
+	second, cancelSecond := b.Next()
+	defer cancelSecond()
+
+	if actual := remaining(t, second); !within(actual, time.Millisecond*900) {
+		t.Errorf("expected about 900ms; actual %s", actual)
+	}
+}
@@ -0,0 +1,106 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"networking/concurrency-patterns/semaphore"
+	"networking/stablity-patterns/decorator"
+	"networking/stablity-patterns/telemetry"
+)
+
+// ErrFull is returned when every slot is taken and the wait queue is full as well.
+var ErrFull = errors.New("bulkhead full")
+
+// Effector The function that interacts with the service
+type Effector func(ctx context.Context) (string, error)
+
+type options struct {
+	tracer trace.Tracer
+	logger telemetry.Logger
+}
+
+// Option configures Bulkhead.
+type Option func(*options)
+
+// WithTracer enables OpenTelemetry spans, without it no span is ever started.
+func WithTracer(t trace.Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// WithLogger sets the Logger that receives the reject events, the default discards them.
+func WithLogger(l telemetry.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{logger: telemetry.Nop()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Bulkhead caps the number of concurrent executions of effector to maxConcurrent, so one slow
+// dependency can't consume every goroutine in the process. Up to maxQueue excess calls wait
+// for a free slot (or their ctx), anything beyond that is rejected right away with ErrFull.
+// maxConcurrent is at least 1, a bulkhead without slots would block every call forever.
+func Bulkhead(effector Effector, maxConcurrent int, maxQueue int, opts ...Option) Effector {
+	o := newOptions(opts)
+	//each running call holds one slot, waiters get them first in, first out.
+	slots := semaphore.New(int64(max(maxConcurrent, 1)))
+	//number of calls currently waiting for a slot.
+	var waiting atomic.Int64
+
+	return func(ctx context.Context) (string, error) {
+		ctx, span := telemetry.Start(ctx, o.tracer, "bulkhead")
+		start := time.Now()
+
+		//fast path, there is a free slot.
+		if !slots.TryAcquire(1) {
+			//reserve a place in the queue, give it back when there was none left.
+			if waiting.Add(1) > int64(maxQueue) {
+				waiting.Add(-1)
+				if span.IsRecording() {
+					span.SetAttributes(attribute.Bool("bulkhead.rejected", true))
+				}
+				telemetry.End(span, ErrFull)
+				o.logger.Log(ctx, telemetry.EventReject, slog.String("pattern", "bulkhead"), slog.Int("max_queue", maxQueue))
+				return "", ErrFull
+			}
+
+			err := slots.Acquire(ctx, 1)
+			waiting.Add(-1)
+			if err != nil {
+				telemetry.End(span, err)
+				return "", err
+			}
+		}
+
+		if span.IsRecording() {
+			span.SetAttributes(attribute.Bool("bulkhead.rejected", false), attribute.Int64("bulkhead.queue_wait_ms", time.Since(start).Milliseconds()))
+		}
+
+		defer slots.Release(1)
+		response, err := effector(ctx)
+		telemetry.End(span, err)
+		return response, err
+	}
+}
+
+// Decorator is Bulkhead as a decorator.Decorator, every effector it decorates gets its own slots.
+func Decorator(maxConcurrent int, maxQueue int, opts ...Option) decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](Bulkhead(Effector(effector), maxConcurrent, maxQueue, opts...))
+	}
+}
@@ -0,0 +1,93 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBulkhead(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+
+	effector := func(ctx context.Context) (string, error) {
+		started <- struct{}{}
+		<-release
+		return "ok", nil
+	}
+
+	withBulkhead := Bulkhead(effector, 2, 1)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+
+	//2 calls take the slots and 1 waits in the queue.
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := withBulkhead(context.Background())
+			errs <- err
+		}()
+	}
+
+	for range 2 {
+		<-started
+	}
+
+	//give the third call time to get into the queue.
+	time.Sleep(time.Millisecond * 50)
+
+	if _, err := withBulkhead(context.Background()); !errors.Is(err, ErrFull) {
+		t.Fatalf("expected %v; actual %v", ErrFull, err)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestBulkheadQueueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	effector := func(ctx context.Context) (string, error) {
+		<-release
+		return "ok", nil
+	}
+
+	withBulkhead := Bulkhead(effector, 1, 1)
+
+	go func() { _, _ = withBulkhead(context.Background()) }()
+	time.Sleep(time.Millisecond * 50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	if _, err := withBulkhead(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestBulkheadNoSlots(t *testing.T) {
+	effector := func(ctx context.Context) (string, error) {
+		return "ok", nil
+	}
+
+	//a size of zero still runs one call at a time instead of blocking forever.
+	withBulkhead := Bulkhead(effector, 0, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := withBulkhead(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
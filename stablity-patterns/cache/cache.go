@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"networking/stablity-patterns/clock"
+	"networking/stablity-patterns/decorator"
+)
+
+// Effector The function that interacts with the service
+type Effector func(ctx context.Context) (string, error)
+
+// KeyFunc derives the cache key of a call from its ctx, e.g. from a request id stored in it.
+type KeyFunc func(ctx context.Context) string
+
+type options struct {
+	stale time.Duration
+	clock clock.Clock
+}
+
+// Option configures a Cache.
+type Option func(*options)
+
+// WithStaleWhileRevalidate keeps serving an expired entry for up to d past its ttl while it
+// is refreshed in the background, callers never wait for the refresh.
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return func(o *options) {
+		o.stale = d
+	}
+}
+
+// WithClock replaces the real clock, mostly so tests can use a clock.Fake.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type entry struct {
+	response string
+	storedAt time.Time
+	//true while a background refresh is running, so only one is started per entry.
+	refreshing bool
+}
+
+/*
+Cache implements the cache-aside pattern: the successful results of an effector are stored by key
+and served for ttl without calling the effector again. Errors are never cached.
+
+With WithStaleWhileRevalidate an entry past its ttl is still served for a while, and the first call
+to see it stale starts a single background refresh. A failed refresh keeps the stale entry, so behind
+a breaker the cache keeps answering while the dependency is down, up to the stale window.
+
+Concurrent misses of the same key all call the effector, put a singleflight.Group behind the cache
+to collapse them.
+*/
+type Cache struct {
+	ttl  time.Duration
+	opts options
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	//last time the dead entries were swept out of entries.
+	sweptAt time.Time
+}
+
+// New creates a Cache whose entries are fresh for ttl.
+func New(ttl time.Duration, opts ...Option) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		opts:    newOptions(opts),
+		entries: make(map[string]*entry),
+	}
+}
+
+// Do returns the cached result of key, calling effector on a miss.
+func (c *Cache) Do(ctx context.Context, key string, effector Effector) (string, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		age := c.opts.clock.Now().Sub(e.storedAt)
+		switch {
+		case age < c.ttl:
+			c.mu.Unlock()
+			return e.response, nil
+		case age < c.ttl+c.opts.stale:
+			if !e.refreshing {
+				e.refreshing = true
+				//the refresh outlives the call, so it must not be cancelled with it.
+				go c.refresh(context.WithoutCancel(ctx), key, e, effector)
+			}
+			c.mu.Unlock()
+			return e.response, nil
+		default:
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+
+	response, err := effector(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.store(key, response)
+	return response, nil
+}
+
+// Wrap puts the cache in front of effector, key picks the entry of each call.
+func (c *Cache) Wrap(key KeyFunc, effector Effector) Effector {
+	return func(ctx context.Context) (string, error) {
+		return c.Do(ctx, key(ctx), effector)
+	}
+}
+
+// Decorator returns Wrap as a decorator.Decorator, every effector it decorates shares this cache.
+func (c *Cache) Decorator(key KeyFunc) decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](c.Wrap(key, Effector(effector)))
+	}
+}
+
+// Invalidate drops the entry of key, the next call executes again.
+func (c *Cache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+func (c *Cache) refresh(ctx context.Context, key string, stale *entry, effector Effector) {
+	response, err := effector(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	//keep serving the stale one, the next stale hit tries again.
+	if err != nil {
+		stale.refreshing = false
+		return
+	}
+
+	//only replace the entry we refreshed, it might have been invalidated or replaced meanwhile.
+	if c.entries[key] == stale {
+		c.entries[key] = &entry{response: response, storedAt: c.opts.clock.Now()}
+	}
+}
+
+func (c *Cache) store(key, response string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.opts.clock.Now()
+	c.sweep(now)
+	c.entries[key] = &entry{response: response, storedAt: now}
+}
+
+// sweep drops the entries past their stale window whose key nobody asked for again, at most once
+// per ttl so storing stays cheap on average. It must be called with mu held.
+func (c *Cache) sweep(now time.Time) {
+	if now.Sub(c.sweptAt) < c.ttl {
+		return
+	}
+	c.sweptAt = now
+
+	for key, e := range c.entries {
+		if now.Sub(e.storedAt) >= c.ttl+c.opts.stale {
+			delete(c.entries, key)
+		}
+	}
+}
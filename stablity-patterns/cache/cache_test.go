@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"networking/stablity-patterns/clock"
+)
+
+func TestCacheTTL(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	c := New(time.Minute, WithClock(fake))
+
+	calls := 0
+	effector := func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 2 {
+			return "", errors.New("down")
+		}
+		return "ok", nil
+	}
+
+	ctx := context.Background()
+	for range 3 {
+		if _, err := c.Do(ctx, "k", effector); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call within the ttl; actual %d", calls)
+	}
+
+	//expired, without a stale window the error goes straight to the caller.
+	fake.Advance(time.Minute)
+	if _, err := c.Do(ctx, "k", effector); err == nil {
+		t.Fatal("expected the error of the effector")
+	}
+
+	//errors are not cached.
+	if _, err := c.Do(ctx, "k", effector); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls; actual %d", calls)
+	}
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	c := New(time.Minute, WithClock(fake), WithStaleWhileRevalidate(time.Minute))
+
+	refreshed := make(chan struct{})
+	release := make(chan struct{})
+	version := "v1"
+	effector := func(ctx context.Context) (string, error) {
+		if version == "v1" {
+			return version, nil
+		}
+		refreshed <- struct{}{}
+		<-release
+		return version, nil
+	}
+
+	ctx := context.Background()
+	if _, err := c.Do(ctx, "k", effector); err != nil {
+		t.Fatal(err)
+	}
+
+	version = "v2"
+	fake.Advance(time.Minute + time.Second)
+
+	//stale, served right away while the refresh runs.
+	for range 2 {
+		res, err := c.Do(ctx, "k", effector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != "v1" {
+			t.Fatalf("expected the stale v1; actual %s", res)
+		}
+	}
+
+	<-refreshed
+	close(release)
+
+	//wait for the refresh to land.
+	deadline := time.Now().Add(time.Second)
+	for {
+		res, _ := c.Do(ctx, "k", effector)
+		if res == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the refreshed v2; actual %s", res)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheSweep(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	c := New(time.Minute, WithClock(fake), WithStaleWhileRevalidate(time.Minute))
+
+	effector := func(ctx context.Context) (string, error) {
+		return "ok", nil
+	}
+
+	ctx := context.Background()
+	for _, key := range []string{"a", "b"} {
+		if _, err := c.Do(ctx, key, effector); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//still within the stale window, kept.
+	fake.Advance(time.Minute)
+	if _, err := c.Do(ctx, "c", effector); err != nil {
+		t.Fatal(err)
+	}
+
+	//a and b are past it and never asked for again, storing d drops them.
+	fake.Advance(time.Minute)
+	if _, err := c.Do(ctx, "d", effector); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) != 2 {
+		t.Errorf("expected only c and d to be kept; actual %d entries", len(c.entries))
+	}
+}
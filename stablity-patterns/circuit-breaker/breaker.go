@@ -1,15 +1,64 @@
-package main
+package circuitbreaker
 
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"networking/stablity-patterns/clock"
+	"networking/stablity-patterns/decorator"
+	"networking/stablity-patterns/telemetry"
 )
 
+// ErrServiceUnreachable is returned by the breaker while it is open and still cooling off.
+var ErrServiceUnreachable = errors.New("service unreachable")
+
 // Circuit represents the function that interacts with a resource.
 type Circuit func(ctx context.Context) (string, error)
 
+type options struct {
+	clock  clock.Clock
+	tracer trace.Tracer
+	logger telemetry.Logger
+}
+
+// Option configures Breaker.
+type Option func(*options)
+
+// WithClock replaces the real clock, mostly so tests can use a clock.Fake.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithTracer enables OpenTelemetry spans, without it no span is ever started.
+func WithTracer(t trace.Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// WithLogger sets the Logger that receives the open, close and reject events, the default discards them.
+func WithLogger(l telemetry.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.Real(), logger: telemetry.Nop()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 /*
 Breaker is a circuit breaker implementation commonly used for backoff strategies
 in resource-intensive operations like database connections or API calls. It protects
@@ -93,17 +142,22 @@ This pattern is commonly used in distributed systems, microservices, and network
 handle transient failures gracefully while avoiding cascading issues or system overload.
 */
 
-func Breaker(circuit Circuit, failureThreshold int) Circuit {
+func Breaker(circuit Circuit, failureThreshold int, opts ...Option) Circuit {
+	o := newOptions(opts)
 
 	//Tracks the number of consecutive failures.
 	consecutiveFailures := 0
 	//Records the last time the circuit was attempted.
-	lastAttempt := time.Now()
+	lastAttempt := o.clock.Now()
 	//Both variables are shared across calls to the returned function. so we need to protect them.
 	var m sync.RWMutex
 
 	return func(ctx context.Context) (string, error) {
+		ctx, span := telemetry.Start(ctx, o.tracer, "breaker")
+
 		m.RLock()
+		failures := consecutiveFailures
+		state := "closed"
 		//results in negative numbers, when it gets into positives then it means
 		//we have reached the threshold so we calculate one last retry time.
 		d := consecutiveFailures - failureThreshold
@@ -111,27 +165,52 @@ func Breaker(circuit Circuit, failureThreshold int) Circuit {
 			//backoff is triggered.
 			shouldRetryAt := lastAttempt.Add(time.Second * 2 << d)
 			//If the current time is still within the cooling-off period, return a "service unavailable" error.
-			if !time.Now().After(shouldRetryAt) {
+			if !o.clock.Now().After(shouldRetryAt) {
 				m.RUnlock()
+				if span.IsRecording() {
+					span.SetAttributes(attribute.String("breaker.state", "open"), attribute.Int("breaker.consecutive_failures", failures))
+				}
+				telemetry.End(span, ErrServiceUnreachable)
+				o.logger.Log(ctx, telemetry.EventReject, slog.String("pattern", "breaker"), slog.Int("consecutive_failures", failures))
 				//still in cooling-off situation, no more request to service.
-				return "", errors.New("service unreachable")
+				return "", ErrServiceUnreachable
 			}
 			//else go ahead and make a request.
+			state = "half-open"
 		}
 		m.RUnlock()
 
+		if span.IsRecording() {
+			span.SetAttributes(attribute.String("breaker.state", state), attribute.Int("breaker.consecutive_failures", failures))
+		}
+
 		response, err := circuit(ctx)
+		telemetry.End(span, err)
 		//we want to modify shared resources
 		m.Lock()
 		defer m.Unlock()
-		lastAttempt = time.Now()
+		lastAttempt = o.clock.Now()
 		//we have error, so we first inc the counter then return the response.
 		if err != nil {
 			consecutiveFailures++
+			//only the failure crossing the threshold opens it, the ones after keep it open.
+			if consecutiveFailures == failureThreshold {
+				o.logger.Log(ctx, telemetry.EventOpen, slog.String("pattern", "breaker"), slog.Any("error", err))
+			}
 			return response, err
 		}
+		if consecutiveFailures >= failureThreshold {
+			o.logger.Log(ctx, telemetry.EventClose, slog.String("pattern", "breaker"))
+		}
 		//we do not have error, so we reset the counter and return the response.
 		consecutiveFailures = 0
 		return response, nil
 	}
 }
+
+// Decorator is Breaker as a decorator.Decorator, every effector it decorates gets its own breaker.
+func Decorator(failureThreshold int, opts ...Option) decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](Breaker(Circuit(effector), failureThreshold, opts...))
+	}
+}
@@ -0,0 +1,151 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"networking/stablity-patterns/clock"
+	"networking/stablity-patterns/telemetry"
+)
+
+func TestBreakerBackoff(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+
+	fail := true
+	calls := 0
+	breaker := Breaker(func(ctx context.Context) (string, error) {
+		calls++
+		if fail {
+			return "", errors.New("down")
+		}
+		return "ok", nil
+	}, 2, WithClock(fake))
+
+	ctx := context.Background()
+	for range 2 {
+		if _, err := breaker(ctx); err == nil {
+			t.Fatal("expected failure")
+		}
+	}
+
+	//open, first backoff is 2s.
+	if _, err := breaker(ctx); !errors.Is(err, ErrServiceUnreachable) {
+		t.Fatalf("expected %v; actual %v", ErrServiceUnreachable, err)
+	}
+
+	fake.Advance(time.Second*2 + time.Millisecond)
+	if _, err := breaker(ctx); err == nil || errors.Is(err, ErrServiceUnreachable) {
+		t.Fatalf("expected the half open call to reach the circuit; actual %v", err)
+	}
+
+	//one more failure, the backoff doubles to 4s.
+	fake.Advance(time.Second * 3)
+	if _, err := breaker(ctx); !errors.Is(err, ErrServiceUnreachable) {
+		t.Fatalf("expected %v; actual %v", ErrServiceUnreachable, err)
+	}
+
+	fail = false
+	fake.Advance(time.Second + time.Millisecond)
+	if _, err := breaker(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 4 {
+		t.Errorf("expected 4 calls to reach the circuit; actual %d", calls)
+	}
+}
+
+type recordingSpan struct {
+	noop.Span
+	attrs map[attribute.Key]attribute.Value
+}
+
+func (s *recordingSpan) IsRecording() bool { return true }
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	s := &recordingSpan{attrs: make(map[attribute.Key]attribute.Value)}
+	t.spans = append(t.spans, s)
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+func TestBreakerTracing(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	tracer := &recordingTracer{}
+
+	breaker := Breaker(func(ctx context.Context) (string, error) {
+		return "", errors.New("down")
+	}, 1, WithClock(fake), WithTracer(tracer))
+
+	ctx := context.Background()
+	_, _ = breaker(ctx)
+	_, _ = breaker(ctx)
+	fake.Advance(time.Second * 3)
+	_, _ = breaker(ctx)
+
+	expected := []string{"closed", "open", "half-open"}
+	if len(tracer.spans) != len(expected) {
+		t.Fatalf("expected %d spans; actual %d", len(expected), len(tracer.spans))
+	}
+
+	for i, state := range expected {
+		if actual := tracer.spans[i].attrs["breaker.state"].AsString(); actual != state {
+			t.Errorf("%d: expected state %q; actual %q", i, state, actual)
+		}
+	}
+}
+
+type recordingLogger struct {
+	events []string
+}
+
+func (l *recordingLogger) Log(ctx context.Context, event string, attrs ...slog.Attr) {
+	l.events = append(l.events, event)
+}
+
+func TestBreakerLogging(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	logger := &recordingLogger{}
+
+	fail := true
+	breaker := Breaker(func(ctx context.Context) (string, error) {
+		if fail {
+			return "", errors.New("down")
+		}
+		return "ok", nil
+	}, 2, WithClock(fake), WithLogger(logger))
+
+	ctx := context.Background()
+	breaker(ctx)
+	breaker(ctx)
+	breaker(ctx)
+
+	fail = false
+	fake.Advance(time.Second*2 + time.Millisecond)
+	if _, err := breaker(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{telemetry.EventOpen, telemetry.EventReject, telemetry.EventClose}
+	if !slices.Equal(logger.events, expected) {
+		t.Errorf("expected %v; actual %v", expected, logger.events)
+	}
+}
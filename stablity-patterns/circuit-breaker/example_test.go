@@ -0,0 +1,54 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"networking/stablity-patterns/clock"
+)
+
+// A dependency fails twice, the breaker opens and fails fast until the 2s backoff passed,
+// then lets a call through again and closes once it succeeds.
+func ExampleBreaker() {
+	fake := clock.NewFake(time.Now())
+
+	down := true
+	withBreaker := Breaker(func(ctx context.Context) (string, error) {
+		if down {
+			return "", errors.New("connection refused")
+		}
+		return "pong", nil
+	}, 2, WithClock(fake))
+
+	call := func() {
+		resp, err := withBreaker(context.Background())
+		if err != nil {
+			fmt.Println("Err:", err)
+			return
+		}
+		fmt.Println("Result:", resp)
+	}
+
+	call()
+	call()
+	//open, the dependency is not even called.
+	call()
+
+	//it came back, but the breaker still waits for the backoff.
+	down = false
+	call()
+
+	fake.Advance(time.Second * 3)
+	call()
+	call()
+
+	// Output:
+	// Err: connection refused
+	// Err: connection refused
+	// Err: service unreachable
+	// Err: service unreachable
+	// Result: pong
+	// Result: pong
+}
@@ -0,0 +1,57 @@
+package clock
+
+import (
+	"time"
+)
+
+// Clock is the subset of the time package the stability patterns depend on, so tests can
+// swap the real clock for a Fake one they control.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Ticker is the part of *time.Ticker the patterns use.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer is the part of *time.Timer the patterns use.
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Real returns the Clock backed by the time package.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
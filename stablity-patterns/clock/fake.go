@@ -0,0 +1,181 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// waiter is anything waiting on the fake clock: an After channel, a ticker or an AfterFunc.
+type waiter struct {
+	at time.Time
+	//non zero for tickers.
+	period time.Duration
+	//set for After and tickers.
+	ch chan time.Time
+	//set for AfterFunc.
+	fn func()
+}
+
+// Fake is a Clock that only moves when told to with Advance, so time based code can be
+// tested deterministically and without sleeping.
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+// NewFake creates a Fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	f.add(&waiter{at: f.Now().Add(d), ch: ch})
+	return ch
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	w := &waiter{at: f.Now().Add(d), period: d, ch: make(chan time.Time, 1)}
+	f.add(w)
+	return &fakeTicker{f: f, w: w}
+}
+
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	w := &waiter{at: f.Now().Add(d), fn: fn}
+	f.add(w)
+	return &fakeTimer{f: f, w: w}
+}
+
+// Waiters returns the number of pending After channels, tickers and timers.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil blocks until at least n waiters are pending, so a test knows the code under
+// test has reached its After/NewTicker/AfterFunc call before it advances the clock.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// Advance moves the clock forward by d and fires everything that became due, in order.
+// AfterFunc callbacks run synchronously before Advance returns.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	f.mu.Unlock()
+
+	for {
+		f.mu.Lock()
+		w := f.next(end)
+		if w == nil {
+			f.now = end
+			f.mu.Unlock()
+			return
+		}
+
+		f.now = w.at
+		now := f.now
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.remove(w)
+		}
+		f.mu.Unlock()
+
+		if w.fn != nil {
+			w.fn()
+			continue
+		}
+
+		//like the real ticker, drop the tick when nobody read the previous one.
+		select {
+		case w.ch <- now:
+		default:
+		}
+	}
+}
+
+// next returns the earliest waiter due at or before end, it must be called with mu held.
+func (f *Fake) next(end time.Time) *waiter {
+	var earliest *waiter
+	for _, w := range f.waiters {
+		if w.at.After(end) {
+			continue
+		}
+		if earliest == nil || w.at.Before(earliest.at) {
+			earliest = w
+		}
+	}
+	return earliest
+}
+
+func (f *Fake) add(w *waiter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+}
+
+// remove drops w and reports whether it was still pending, it must be called with mu held.
+func (f *Fake) remove(w *waiter) bool {
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.w.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
+
+type fakeTimer struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.remove(t.w)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	active := t.f.remove(t.w)
+	t.w.at = t.f.now.Add(d)
+	t.f.waiters = append(t.f.waiters, t.w)
+	t.f.cond.Broadcast()
+	return active
+}
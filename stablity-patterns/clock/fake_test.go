@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	after := f.After(time.Second)
+	ticker := f.NewTicker(time.Millisecond * 400)
+	fired := 0
+	timer := f.AfterFunc(time.Millisecond*500, func() { fired++ })
+
+	f.Advance(time.Millisecond * 450)
+
+	select {
+	case <-after:
+		t.Fatal("expected After to not fire before its time")
+	default:
+	}
+
+	if tick := <-ticker.C(); !tick.Equal(start.Add(time.Millisecond * 400)) {
+		t.Errorf("expected tick at 400ms; actual %s", tick.Sub(start))
+	}
+
+	//pushes the timer to 950ms.
+	if !timer.Reset(time.Millisecond * 500) {
+		t.Error("expected timer to still be active")
+	}
+
+	f.Advance(time.Millisecond * 550)
+
+	if actual := <-after; !actual.Equal(start.Add(time.Second)) {
+		t.Errorf("expected After to fire at 1s; actual %s", actual.Sub(start))
+	}
+
+	if fired != 1 {
+		t.Errorf("expected AfterFunc to fire once; actual %d", fired)
+	}
+
+	if actual := f.Now(); !actual.Equal(start.Add(time.Second)) {
+		t.Errorf("expected now to be 1s; actual %s", actual.Sub(start))
+	}
+
+	ticker.Stop()
+	if actual := f.Waiters(); actual != 0 {
+		t.Errorf("expected no waiters left; actual %d", actual)
+	}
+}
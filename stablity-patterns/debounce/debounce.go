@@ -1,9 +1,12 @@
-package main
+package debounce
 
 import (
 	"context"
 	"sync"
 	"time"
+
+	"networking/stablity-patterns/clock"
+	"networking/stablity-patterns/decorator"
 )
 
 /*
@@ -25,14 +28,48 @@ limits according to time period.
 
 type Circuit func(ctx context.Context) (string, error)
 
+type options struct {
+	maxWait time.Duration
+	clock   clock.Clock
+}
+
+// Option configures the behaviour of a debounced circuit.
+type Option func(*options)
+
+// WithMaxWait guarantees the circuit runs at least once every d even under a continuous
+// stream of calls, which would otherwise keep pushing the execution forward forever.
+func WithMaxWait(d time.Duration) Option {
+	return func(o *options) {
+		o.maxWait = d
+	}
+}
+
+// WithClock replaces the real clock, mostly so tests can use a clock.Fake.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 // DebounceVersion1 It prevents rapid consecutive executions of circuit by enforcing a "cooldown period" (d) between calls.
 // pattern designed to delay function execution until after a defined period (d) has passed without further calls,
 // while also handling concurrency
 // If this returned function (Circuit) is called repeatedly, it postpones the actual execution (circuit(ctx))
 // until a specified duration (d) has passed since the last call.
-func DebounceVersion1(circuit Circuit, d time.Duration) Circuit {
+func DebounceVersion1(circuit Circuit, d time.Duration, opts ...Option) Circuit {
+	o := newOptions(opts)
 	//starts with the zero value (time.Time{}) and is updated every time the circuit runs.
 	var threshold time.Time
+	//the last time the circuit actually ran, used to enforce the max wait.
+	var lastRun time.Time
 	//caches the last successful result returned by the circuit function.
 	var result string
 	//caches the last error returned by the circuit function
@@ -44,17 +81,22 @@ func DebounceVersion1(circuit Circuit, d time.Duration) Circuit {
 		m.Lock()
 		defer func() {
 			//at the end we update the next threshold to be current time + d
-			threshold = time.Now().Add(d)
+			threshold = o.clock.Now().Add(d)
 			m.Unlock()
 		}()
 
+		now := o.clock.Now()
 		//means the cooldown period (d) has not yet passed.
-		if time.Now().Before(threshold) {
-			//The function returns the cached values (result and err) from the last execution of circuit.
-			return result, err
+		if now.Before(threshold) {
+			//unless the calls kept coming for longer than max wait, then we run it anyway.
+			if o.maxWait <= 0 || now.Before(lastRun.Add(o.maxWait)) {
+				//The function returns the cached values (result and err) from the last execution of circuit.
+				return result, err
+			}
 		}
 
 		//cooldown period has passed
+		lastRun = now
 		result, err = circuit(ctx)
 		//if it fails or succeeds we cache results and return them
 		return result, err
@@ -62,11 +104,15 @@ func DebounceVersion1(circuit Circuit, d time.Duration) Circuit {
 }
 
 // DebounceVersion2 multiple calls come in quick succession, only the last call's result will be returned after the debounce delay.
-func DebounceVersion2(circuit Circuit, d time.Duration) Circuit {
+// With WithMaxWait the circuit also runs once the max wait has elapsed since the first call of the cluster.
+func DebounceVersion2(circuit Circuit, d time.Duration, opts ...Option) Circuit {
+	o := newOptions(opts)
 	//tracks the earliest time when the circuit function can execute again. It is initialized to the current time.
-	threshold := time.Now()
+	threshold := o.clock.Now()
+	//the latest time the circuit must run regardless of incoming calls, zero when there is no max wait.
+	var deadline time.Time
 	//periodic timer (time.Ticker) that checks whether the cooldown period has passed.
-	var ticker *time.Ticker
+	var ticker clock.Ticker
 	//caches
 	var result string
 	var err error
@@ -81,11 +127,15 @@ func DebounceVersion2(circuit Circuit, d time.Duration) Circuit {
 		defer m.Unlock()
 		//Every call to the returned function updates threshold to the current time + d (the debounce period).
 		//This ensures that the circuit cannot execute until the cooldown period expires.
-		threshold = time.Now().Add(d)
+		threshold = o.clock.Now().Add(d)
 		//Ensures the following initialization logic runs only once, regardless of how many times the returned function is called
 		once.Do(func() {
+			//the first call of a cluster starts the max wait clock.
+			if o.maxWait > 0 {
+				deadline = o.clock.Now().Add(o.maxWait)
+			}
 			//triggering every 100ms to check whether the debounce delay has elapsed.
-			ticker = time.NewTicker(time.Millisecond * 100)
+			ticker = o.clock.NewTicker(time.Millisecond * 100)
 
 			go func() {
 				defer func() {
@@ -99,9 +149,10 @@ func DebounceVersion2(circuit Circuit, d time.Duration) Circuit {
 
 				for {
 					select {
-					case <-ticker.C:
+					case <-ticker.C():
 						m.Lock()
-						if time.Now().After(threshold) {
+						now := o.clock.Now()
+						if now.After(threshold) || (!deadline.IsZero() && now.After(deadline)) {
 							result, err = circuit(ctx)
 							m.Unlock()
 							return
@@ -119,3 +170,10 @@ func DebounceVersion2(circuit Circuit, d time.Duration) Circuit {
 		return result, err
 	}
 }
+
+// Decorator is DebounceVersion1 as a decorator.Decorator.
+func Decorator(d time.Duration, opts ...Option) decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](DebounceVersion1(Circuit(effector), d, opts...))
+	}
+}
@@ -0,0 +1,211 @@
+package debounce
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"networking/stablity-patterns/clock"
+)
+
+func TestDebounceMaxWait(t *testing.T) {
+	var calls atomic.Int32
+	circuit := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "ok", nil
+	}
+
+	testCases := []struct {
+		name      string
+		debounced Circuit
+	}{
+		{"version1", DebounceVersion1(circuit, time.Millisecond*200, WithMaxWait(time.Millisecond*300))},
+		{"version2", DebounceVersion2(circuit, time.Millisecond*200, WithMaxWait(time.Millisecond*300))},
+	}
+
+	for _, c := range testCases {
+		calls.Store(0)
+
+		//keep calling faster than the debounce period so without max wait it would starve.
+		for range 20 {
+			if _, err := c.debounced(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(time.Millisecond * 50)
+		}
+
+		if actual := calls.Load(); actual < 2 {
+			t.Errorf("%s: expected at least 2 executions; actual %d", c.name, actual)
+		}
+	}
+}
+
+func TestDebouncerFlushAndCancel(t *testing.T) {
+	var calls atomic.Int32
+	fn := func(ctx context.Context, _ struct{}) (string, error) {
+		calls.Add(1)
+		return "ok", nil
+	}
+
+	db := NewDebouncer(fn, time.Hour, nil)
+
+	for range 3 {
+		if _, err := db.Call(context.Background(), struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := db.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res != "ok" {
+		t.Errorf("expected result %q; actual %q", "ok", res)
+	}
+
+	if actual := calls.Load(); actual != 1 {
+		t.Fatalf("expected 1 execution after flush; actual %d", actual)
+	}
+
+	//nothing pending anymore so flush must not run the circuit again.
+	if _, err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := calls.Load(); actual != 1 {
+		t.Fatalf("expected 1 execution after second flush; actual %d", actual)
+	}
+
+	if _, err := db.Call(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Cancel()
+
+	if _, err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := calls.Load(); actual != 1 {
+		t.Fatalf("expected cancelled invocation to be dropped; actual %d executions", actual)
+	}
+}
+
+func TestDebouncerTrailingCall(t *testing.T) {
+	done := make(chan struct{})
+	fn := func(ctx context.Context, _ struct{}) (string, error) {
+		close(done)
+		return "ok", nil
+	}
+
+	db := NewDebouncer(fn, time.Millisecond*50, nil)
+	if _, err := db.Call(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected pending invocation to run after the debounce period")
+	}
+}
+
+func TestDebouncerCoalesce(t *testing.T) {
+	var got []string
+	fn := func(ctx context.Context, keys []string) (int, error) {
+		got = keys
+		return len(keys), nil
+	}
+
+	//dedupe the keys of every suppressed call into the pending batch.
+	dedupe := func(pending, next []string) []string {
+		for _, k := range next {
+			if !slices.Contains(pending, k) {
+				pending = append(pending, k)
+			}
+		}
+		return pending
+	}
+
+	db := NewDebouncer(fn, time.Hour, dedupe)
+
+	calls := [][]string{{"a"}, {"b", "a"}, {"c"}, {"b"}}
+	for _, keys := range calls {
+		if _, err := db.Call(context.Background(), keys); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := db.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"a", "b", "c"}
+	if n != len(expected) || !slices.Equal(got, expected) {
+		t.Fatalf("expected %v; actual %v", expected, got)
+	}
+}
+
+func TestDebouncerMaxWaitFakeClock(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+
+	calls := 0
+	fn := func(ctx context.Context, _ struct{}) (string, error) {
+		calls++
+		return "ok", nil
+	}
+
+	db := NewDebouncer(fn, time.Second, nil, WithMaxWait(time.Second*3), WithClock(fake))
+
+	//a call every 500ms keeps pushing the 1s debounce forward, only max wait gets it to run.
+	for range 10 {
+		if _, err := db.Call(context.Background(), struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+		fake.Advance(time.Millisecond * 500)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected max wait to force 1 execution in 5s; actual %d", calls)
+	}
+}
+
+func TestDebouncerFlushWaitsForRunning(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(ctx context.Context, n int) (int, error) {
+		close(started)
+		<-release
+		return n, nil
+	}
+
+	fake := clock.NewFake(time.Now())
+	db := NewDebouncer(fn, time.Second, nil, WithClock(fake))
+	if _, err := db.Call(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+
+	//the timer took the pending call, fn is running. Advance runs it synchronously.
+	go fake.Advance(time.Second)
+	<-started
+
+	flushed := make(chan int, 1)
+	go func() {
+		res, _ := db.Flush()
+		flushed <- res
+	}()
+
+	select {
+	case res := <-flushed:
+		t.Fatalf("expected Flush to wait for the running call; returned %d", res)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(release)
+	if res := <-flushed; res != 7 {
+		t.Errorf("expected the result of the running call 7; actual %d", res)
+	}
+}
@@ -0,0 +1,191 @@
+package debounce
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"networking/stablity-patterns/clock"
+)
+
+// Func is the generic counterpart of Circuit, it takes an argument of type In and returns Out.
+type Func[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Coalescer merges the argument of a suppressed call into the pending one, e.g. deduping
+// keys or appending batches, so the single real invocation sees all of them.
+type Coalescer[In any] func(pending, next In) In
+
+// invocation is a run of fn taken off the pending state, done is closed once result and err are set.
+type invocation[Out any] struct {
+	done   chan struct{}
+	result Out
+	err    error
+}
+
+// Debouncer is a trailing edge debouncer like DebounceVersion2, but the pending invocation
+// can be driven explicitly with Flush and Cancel so shutdown paths can drain it deterministically.
+type Debouncer[In, Out any] struct {
+	fn       Func[In, Out]
+	coalesce Coalescer[In]
+	d        time.Duration
+	opts     options
+
+	mu sync.Mutex
+	//fires the pending invocation once the calls have settled.
+	timer clock.Timer
+	//true while there is an invocation waiting to run.
+	pending bool
+	//ctx of the latest call, the pending invocation runs with it.
+	ctx context.Context
+	//argument of the pending invocation, coalesced from every call of the cluster.
+	in In
+	//first call of the current cluster, used to enforce the max wait.
+	first time.Time
+	//the latest invocation taken off the pending state, it may still be running.
+	last *invocation[Out]
+	//caches
+	result Out
+	err    error
+}
+
+// NewDebouncer creates a Debouncer that runs fn once no call has been made for d. If coalesce
+// is nil the argument of the latest call wins.
+func NewDebouncer[In, Out any](fn Func[In, Out], d time.Duration, coalesce Coalescer[In], opts ...Option) *Debouncer[In, Out] {
+	return &Debouncer[In, Out]{
+		fn:       fn,
+		coalesce: coalesce,
+		d:        d,
+		opts:     newOptions(opts),
+	}
+}
+
+// Call schedules an invocation of fn with in and returns the cached result of the last one.
+func (db *Debouncer[In, Out]) Call(ctx context.Context, in In) (Out, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := db.opts.clock.Now()
+	switch {
+	case !db.pending:
+		db.pending = true
+		db.first = now
+		db.in = in
+	case db.coalesce != nil:
+		db.in = db.coalesce(db.in, in)
+	default:
+		db.in = in
+	}
+	db.ctx = ctx
+
+	delay := db.d
+	if db.opts.maxWait > 0 {
+		//never push the execution past the max wait of this cluster.
+		if left := db.first.Add(db.opts.maxWait).Sub(now); left < delay {
+			delay = left
+		}
+	}
+
+	if db.timer == nil {
+		db.timer = db.opts.clock.AfterFunc(delay, db.fire)
+	} else {
+		db.timer.Reset(delay)
+	}
+
+	return db.result, db.err
+}
+
+// Flush runs the pending invocation right away and returns its result. If nothing is pending
+// but the timer already started an invocation, it waits for that one and returns its result,
+// otherwise it returns the cached result of the last invocation.
+func (db *Debouncer[In, Out]) Flush() (Out, error) {
+	db.mu.Lock()
+	if db.timer != nil {
+		db.timer.Stop()
+	}
+	ctx, in, ok := db.take()
+	if !ok {
+		last := db.last
+		if last == nil {
+			defer db.mu.Unlock()
+			return db.result, db.err
+		}
+		db.mu.Unlock()
+
+		<-last.done
+		return last.result, last.err
+	}
+	inv := db.begin()
+	db.mu.Unlock()
+
+	return db.run(ctx, in, inv)
+}
+
+// Cancel drops the pending invocation, if any, without running it.
+func (db *Debouncer[In, Out]) Cancel() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.timer != nil {
+		db.timer.Stop()
+	}
+	db.take()
+}
+
+func (db *Debouncer[In, Out]) fire() {
+	db.mu.Lock()
+	ctx, in, ok := db.take()
+	var inv *invocation[Out]
+	if ok {
+		inv = db.begin()
+	}
+	db.mu.Unlock()
+
+	//either flushed or cancelled in the meantime.
+	if !ok {
+		return
+	}
+
+	_, _ = db.run(ctx, in, inv)
+}
+
+// take clears the pending invocation and returns its ctx and argument, it must be called with mu held.
+func (db *Debouncer[In, Out]) take() (context.Context, In, bool) {
+	var zero In
+	if !db.pending {
+		return nil, zero, false
+	}
+
+	ctx, in := db.ctx, db.in
+	db.pending = false
+	db.ctx = nil
+	db.in = zero
+	db.first = time.Time{}
+	return ctx, in, true
+}
+
+// begin records the invocation about to run so Flush can wait for it, it must be called with mu held.
+func (db *Debouncer[In, Out]) begin() *invocation[Out] {
+	inv := &invocation[Out]{done: make(chan struct{})}
+	db.last = inv
+	return inv
+}
+
+func (db *Debouncer[In, Out]) run(ctx context.Context, in In, inv *invocation[Out]) (Out, error) {
+	var result Out
+	err := ctx.Err()
+	//caller went away before we got to run it.
+	if err == nil {
+		result, err = db.fn(ctx, in)
+	}
+
+	db.mu.Lock()
+	db.result, db.err = result, err
+	if db.last == inv {
+		db.last = nil
+	}
+	db.mu.Unlock()
+
+	inv.result, inv.err = result, err
+	close(inv.done)
+	return result, err
+}
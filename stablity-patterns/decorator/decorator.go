@@ -0,0 +1,26 @@
+package decorator
+
+import (
+	"context"
+)
+
+// Effector is the function that interacts with the service. Every stability pattern has its own
+// named Effector/Circuit type with this same shape, so they convert to and from it freely.
+type Effector[T any] func(ctx context.Context) (T, error)
+
+// Decorator wraps an Effector with extra behaviour. Every stability pattern provides one, and so
+// can third-party code (tracing, logging), so they all slot into the same pipeline.
+type Decorator[T any] func(Effector[T]) Effector[T]
+
+// Chain combines decorators into one, the first decorator is the outermost:
+// Chain(a, b, c)(e) is a(b(c(e))).
+func Chain[T any](decorators ...Decorator[T]) Decorator[T] {
+	return func(effector Effector[T]) Effector[T] {
+		for i := len(decorators) - 1; i >= 0; i-- {
+			if d := decorators[i]; d != nil {
+				effector = d(effector)
+			}
+		}
+		return effector
+	}
+}
@@ -0,0 +1,39 @@
+package decorator
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+
+	trace := func(name string) Decorator[int] {
+		return func(next Effector[int]) Effector[int] {
+			return func(ctx context.Context) (int, error) {
+				order = append(order, name)
+				return next(ctx)
+			}
+		}
+	}
+
+	effector := Chain(trace("outer"), nil, trace("middle"), trace("inner"))(func(ctx context.Context) (int, error) {
+		order = append(order, "effector")
+		return 42, nil
+	})
+
+	n, err := effector(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 42 {
+		t.Errorf("expected %d; actual %d", 42, n)
+	}
+
+	expected := []string{"outer", "middle", "inner", "effector"}
+	if !slices.Equal(order, expected) {
+		t.Errorf("expected %v; actual %v", expected, order)
+	}
+}
@@ -0,0 +1,45 @@
+package decorator_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"networking/stablity-patterns/bulkhead"
+	circuitbreaker "networking/stablity-patterns/circuit-breaker"
+	"networking/stablity-patterns/decorator"
+	"networking/stablity-patterns/fallback"
+	"networking/stablity-patterns/retry"
+	"networking/stablity-patterns/timeout"
+)
+
+func ExampleChain() {
+	logging := func(next decorator.Effector[string]) decorator.Effector[string] {
+		return func(ctx context.Context) (string, error) {
+			response, err := next(ctx)
+			fmt.Println("logging:", response, err)
+			return response, err
+		}
+	}
+
+	pipeline := decorator.Chain(
+		logging,
+		fallback.Decorator(func(ctx context.Context) (string, error) {
+			return "fallback", nil
+		}),
+		retry.Decorator(1, time.Millisecond),
+		circuitbreaker.Decorator(5),
+		timeout.Decorator(time.Second),
+		bulkhead.Decorator(10, 0),
+	)
+
+	effector := pipeline(func(ctx context.Context) (string, error) {
+		return "", errors.New("down")
+	})
+
+	_, _ = effector(context.Background())
+
+	// Output:
+	// logging: fallback <nil>
+}
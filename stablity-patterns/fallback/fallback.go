@@ -0,0 +1,81 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"networking/stablity-patterns/decorator"
+	"networking/stablity-patterns/telemetry"
+)
+
+// Effector The function that interacts with the service
+type Effector func(ctx context.Context) (string, error)
+
+type options struct {
+	shouldFallback func(err error) bool
+	logger         telemetry.Logger
+}
+
+// Option configures when the fallback is taken.
+type Option func(*options)
+
+// When sets the predicate that decides which errors of the primary trigger the fallback,
+// e.g. only circuitbreaker.ErrServiceUnreachable. By default every error does.
+func When(pred func(err error) bool) Option {
+	return func(o *options) {
+		o.shouldFallback = pred
+	}
+}
+
+// WithLogger sets the Logger that receives the fallback events, the default discards them.
+func WithLogger(l telemetry.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		shouldFallback: func(err error) bool { return true },
+		logger:         telemetry.Nop(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Fallback calls primary and when it fails (or the breaker in front of it is open) calls
+// secondary instead. If secondary fails too both errors are returned.
+func Fallback(primary, secondary Effector, opts ...Option) Effector {
+	o := newOptions(opts)
+
+	return func(ctx context.Context) (string, error) {
+		response, err := primary(ctx)
+		if err == nil || !o.shouldFallback(err) {
+			return response, err
+		}
+
+		o.logger.Log(ctx, telemetry.EventFallback, slog.String("pattern", "fallback"), slog.Any("error", err))
+		response, fallbackErr := secondary(ctx)
+		if fallbackErr != nil {
+			return "", errors.Join(err, fallbackErr)
+		}
+		return response, nil
+	}
+}
+
+// Static is Fallback with a fixed value as the secondary path.
+func Static(primary Effector, value string, opts ...Option) Effector {
+	return Fallback(primary, func(ctx context.Context) (string, error) {
+		return value, nil
+	}, opts...)
+}
+
+// Decorator is Fallback as a decorator.Decorator, the decorated effector is the primary.
+func Decorator(secondary Effector, opts ...Option) decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](Fallback(Effector(effector), secondary, opts...))
+	}
+}
@@ -0,0 +1,80 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	circuitbreaker "networking/stablity-patterns/circuit-breaker"
+)
+
+func TestFallback(t *testing.T) {
+	errDown := errors.New("down")
+	errBad := errors.New("bad request")
+
+	failing := func(err error) Effector {
+		return func(ctx context.Context) (string, error) {
+			return "", err
+		}
+	}
+
+	secondary := func(ctx context.Context) (string, error) {
+		return "secondary", nil
+	}
+
+	onlyDown := When(func(err error) bool { return errors.Is(err, errDown) })
+
+	testCases := []struct {
+		name     string
+		effector Effector
+		response string
+		err      error
+	}{
+		{"primary succeeds", Fallback(secondary, failing(errDown)), "secondary", nil},
+		{"primary fails", Fallback(failing(errDown), secondary), "secondary", nil},
+		{"predicate matches", Fallback(failing(errDown), secondary, onlyDown), "secondary", nil},
+		{"predicate does not match", Fallback(failing(errBad), secondary, onlyDown), "", errBad},
+		{"both fail", Fallback(failing(errDown), failing(errBad)), "", errBad},
+		{"static", Static(failing(errDown), "static"), "static", nil},
+	}
+
+	for _, c := range testCases {
+		response, err := c.effector(context.Background())
+		if response != c.response {
+			t.Errorf("%s: expected response %q; actual %q", c.name, c.response, response)
+		}
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: expected error %v; actual %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestFallbackBreakerOpen(t *testing.T) {
+	calls := 0
+	breaker := circuitbreaker.Breaker(func(ctx context.Context) (string, error) {
+		calls++
+		return "", errors.New("down")
+	}, 1)
+
+	//only fall back while the breaker is open, the actual failures are surfaced.
+	withFallback := Static(Effector(breaker), "cached", When(func(err error) bool {
+		return errors.Is(err, circuitbreaker.ErrServiceUnreachable)
+	}))
+
+	if _, err := withFallback(context.Background()); err == nil {
+		t.Fatal("expected the first failure to be returned")
+	}
+
+	response, err := withFallback(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response != "cached" {
+		t.Errorf("expected %q; actual %q", "cached", response)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected the open breaker to stop calls; actual %d calls", calls)
+	}
+}
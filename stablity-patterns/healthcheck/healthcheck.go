@@ -0,0 +1,252 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknown is returned by Status for a dependency that was never registered.
+var ErrUnknown = errors.New("unknown dependency")
+
+// Status is the state of a dependency as seen by the checker.
+type Status int
+
+const (
+	// Unknown means the dependency has not been probed yet.
+	Unknown Status = iota
+	Up
+	Down
+)
+
+func (s Status) String() string {
+	switch s {
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON renders the status as its name.
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Probe checks a dependency once, a nil error means it's up.
+type Probe func(ctx context.Context) error
+
+// TCPProbe considers addr up when a TCP connection can be established.
+func TCPProbe(addr string) Probe {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("dial: %w", err)
+		}
+		return conn.Close()
+	}
+}
+
+// HTTPProbe considers url up when a GET returns a status below 400.
+func HTTPProbe(client *http.Client, url string) Probe {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("new request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("do: %w", err)
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// Result is the current view of one dependency.
+type Result struct {
+	Status Status    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+type dependency struct {
+	probe  Probe
+	result Result
+	//consecutive probe results disagreeing with the current status.
+	streak int
+}
+
+/*
+Checker periodically probes the registered dependencies and keeps an up/down state for each.
+
+To suppress flapping a dependency only changes state after threshold consecutive probes
+disagree with its current state, a single lost packet doesn't mark it down and a single lucky
+probe doesn't mark it up. The aggregate is available through Status and Results, and the
+checker is an http.Handler itself, so it can be mounted as a health endpoint.
+*/
+type Checker struct {
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+
+	mu   sync.RWMutex
+	deps map[string]*dependency
+}
+
+// New creates a Checker probing every interval, giving each probe timeout to finish.
+func New(interval, timeout time.Duration, threshold int) *Checker {
+	return &Checker{
+		interval:  interval,
+		timeout:   timeout,
+		threshold: max(threshold, 1),
+		deps:      make(map[string]*dependency),
+	}
+}
+
+// Register adds a dependency under name, replacing any previous one.
+func (c *Checker) Register(name string, probe Probe) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deps[name] = &dependency{probe: probe}
+}
+
+// Run probes all dependencies right away and then every interval until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckAll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// CheckAll probes every dependency concurrently once and waits for the results.
+func (c *Checker) CheckAll(ctx context.Context) {
+	c.mu.RLock()
+	probes := make(map[string]Probe, len(c.deps))
+	for name, dep := range c.deps {
+		probes[name] = dep.probe
+	}
+	c.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	wg.Add(len(probes))
+
+	for name, probe := range probes {
+		go func() {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			c.record(name, probe(probeCtx))
+		}()
+	}
+
+	wg.Wait()
+}
+
+// Status returns the state of the dependency registered under name.
+func (c *Checker) Status(name string) (Status, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	dep, ok := c.deps[name]
+	if !ok {
+		return Unknown, ErrUnknown
+	}
+	return dep.result.Status, nil
+}
+
+// Results returns a snapshot of every dependency.
+func (c *Checker) Results() map[string]Result {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	results := make(map[string]Result, len(c.deps))
+	for name, dep := range c.deps {
+		results[name] = dep.result
+	}
+	return results
+}
+
+// Healthy reports whether every dependency is up.
+func (c *Checker) Healthy() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, dep := range c.deps {
+		if dep.result.Status != Up {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP writes the results as JSON, with 200 when healthy and 503 otherwise.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code := http.StatusOK
+	if !c.Healthy() {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(c.Results())
+}
+
+func (c *Checker) record(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dep, ok := c.deps[name]
+	if !ok {
+		return
+	}
+
+	observed := Up
+	if err != nil {
+		observed = Down
+	}
+
+	switch {
+	case dep.result.Status == Unknown:
+		//first result is taken as is, there is nothing to flap from.
+		dep.result.Status = observed
+		dep.result.Since = time.Now()
+	case observed == dep.result.Status:
+		dep.streak = 0
+	default:
+		dep.streak++
+		if dep.streak >= c.threshold {
+			dep.result.Status = observed
+			dep.result.Since = time.Now()
+			dep.streak = 0
+		}
+	}
+
+	dep.result.Error = ""
+	if err != nil {
+		dep.result.Error = err.Error()
+	}
+}
@@ -0,0 +1,88 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckerFlappingSuppression(t *testing.T) {
+	fail := false
+	c := New(time.Hour, time.Second, 2)
+	c.Register("db", func(ctx context.Context) error {
+		if fail {
+			return errors.New("down")
+		}
+		return nil
+	})
+
+	ctx := context.Background()
+	testCases := []struct {
+		fail     bool
+		expected Status
+	}{
+		{false, Up},
+		{true, Up},
+		{false, Up},
+		{true, Up},
+		{true, Down},
+		{false, Down},
+		{false, Up},
+	}
+
+	for i, tc := range testCases {
+		fail = tc.fail
+		c.CheckAll(ctx)
+
+		actual, err := c.Status("db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != tc.expected {
+			t.Errorf("%d: expected %s; actual %s", i, tc.expected, actual)
+		}
+	}
+
+	if _, err := c.Status("cache"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("expected %v; actual %v", ErrUnknown, err)
+	}
+}
+
+func TestCheckerProbesAndHandler(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	c := New(time.Hour, time.Second, 1)
+	c.Register("web", HTTPProbe(ts.Client(), ts.URL))
+	c.Register("tcp", TCPProbe(ts.Listener.Addr().String()))
+	c.CheckAll(context.Background())
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://test/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d; actual %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	//nothing listens there anymore.
+	c.Register("closed", TCPProbe(addr))
+	c.CheckAll(context.Background())
+
+	w = httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://test/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected %d; actual %d: %s", http.StatusServiceUnavailable, w.Code, w.Body)
+	}
+}
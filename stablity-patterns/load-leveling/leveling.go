@@ -0,0 +1,223 @@
+package loadleveling
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"networking/stablity-patterns/telemetry"
+)
+
+var (
+	// ErrQueueFull is returned by Enqueue with the Reject policy when the queue is full.
+	ErrQueueFull = errors.New("queue full")
+	// ErrClosed is returned by Enqueue after Shutdown has been called.
+	ErrClosed = errors.New("queue closed")
+)
+
+// Policy decides what Enqueue does when the queue is full.
+type Policy int
+
+const (
+	// Block waits for space in the queue or for the producer's ctx.
+	Block Policy = iota
+	// DropOldest discards the oldest queued item to make room for the new one.
+	DropOldest
+	// Reject returns ErrQueueFull right away.
+	Reject
+)
+
+// Task is a unit of work handed to the queue.
+type Task func(ctx context.Context)
+
+// Option configures a Leveler.
+type Option func(*Leveler)
+
+// WithLogger sets the Logger that receives the reject and drop events, the default discards them.
+func WithLogger(logger telemetry.Logger) Option {
+	return func(l *Leveler) {
+		l.logger = logger
+	}
+}
+
+/*
+Leveler implements queue-based load leveling: producers enqueue tasks into a bounded queue that
+a fixed number of workers consume at a controlled rate. Bursts from producers are absorbed by the
+queue instead of hitting the dependency, which only ever sees the steady rate.
+
+Where Throttle rejects calls above a rate, the leveler smooths them out, and the overflow policy
+decides what happens once the burst is bigger than the queue.
+*/
+type Leveler struct {
+	policy Policy
+	logger telemetry.Logger
+	queue  chan Task
+	//shared by all workers, each tick lets one task start. nil means no rate limit.
+	ticker *time.Ticker
+
+	//protects closed, and makes sure nobody sends on queue once it's closed.
+	mu     sync.RWMutex
+	closed bool
+	//closed by Shutdown before it takes mu, wakes the producers blocked on a full queue.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	//ctx handed to tasks, cancelled when Shutdown gives up on draining.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wg      sync.WaitGroup
+	dropped atomic.Int64
+}
+
+// New creates a Leveler with a queue of size items consumed by workers goroutines, starting at
+// most one task every interval. A zero interval consumes as fast as the workers can. DropOldest
+// and Reject need room for at least one task, their queue is never smaller.
+func New(size int, workers int, interval time.Duration, policy Policy, opts ...Option) *Leveler {
+	//without a slot DropOldest has nothing to drop and spins, and Reject turns everything away.
+	if policy == DropOldest || policy == Reject {
+		size = max(size, 1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l := &Leveler{
+		policy: policy,
+		logger: telemetry.Nop(),
+		queue:  make(chan Task, size),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if interval > 0 {
+		l.ticker = time.NewTicker(interval)
+	}
+
+	l.wg.Add(workers)
+	for range workers {
+		go l.worker()
+	}
+
+	return l
+}
+
+// Dropped returns the number of tasks discarded by the DropOldest policy or by an expired Shutdown.
+func (l *Leveler) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Enqueue hands task to the queue, applying the overflow policy when the queue is full.
+func (l *Leveler) Enqueue(ctx context.Context, task Task) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	switch l.policy {
+	case Reject:
+		select {
+		case l.queue <- task:
+			return nil
+		default:
+			l.logger.Log(ctx, telemetry.EventReject, slog.String("pattern", "load-leveling"))
+			return ErrQueueFull
+		}
+
+	case DropOldest:
+		for {
+			select {
+			case l.queue <- task:
+				return nil
+			default:
+			}
+
+			//make room, a worker might have taken it in the meantime, that's fine too.
+			select {
+			case <-l.queue:
+				l.dropped.Add(1)
+				l.logger.Log(ctx, telemetry.EventDrop, slog.String("pattern", "load-leveling"), slog.String("reason", "oldest"))
+			default:
+			}
+		}
+
+	default:
+		select {
+		case l.queue <- task:
+			return nil
+		case <-l.done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Shutdown stops accepting tasks and waits for the workers to drain the queue. Producers blocked
+// on a full queue get ErrClosed. If ctx is done first the remaining tasks are dropped, running ones
+// see their ctx cancelled, and ctx.Err() is returned without waiting for them to return.
+func (l *Leveler) Shutdown(ctx context.Context) error {
+	//a blocked Enqueue holds mu for reading, let it go before asking for the write lock.
+	l.closeOnce.Do(func() { close(l.done) })
+
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	defer func() {
+		if l.ticker != nil {
+			l.ticker.Stop()
+		}
+	}()
+
+	select {
+	case <-done:
+		l.cancel()
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+func (l *Leveler) worker() {
+	defer l.wg.Done()
+
+	for task := range l.queue {
+		//shutdown gave up, just empty the queue.
+		if l.ctx.Err() != nil {
+			l.dropped.Add(1)
+			l.logger.Log(l.ctx, telemetry.EventDrop, slog.String("pattern", "load-leveling"), slog.String("reason", "shutdown"))
+			continue
+		}
+
+		if l.ticker != nil {
+			select {
+			case <-l.ticker.C:
+			case <-l.ctx.Done():
+				l.dropped.Add(1)
+				l.logger.Log(l.ctx, telemetry.EventDrop, slog.String("pattern", "load-leveling"), slog.String("reason", "shutdown"))
+				continue
+			}
+		}
+
+		task(l.ctx)
+	}
+}
@@ -0,0 +1,156 @@
+package loadleveling
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLevelerPolicies(t *testing.T) {
+	release := make(chan struct{})
+	blocking := func(ctx context.Context) { <-release }
+
+	l := New(1, 1, 0, Reject)
+	//occupy the worker and then the single queue slot.
+	if err := l.Enqueue(context.Background(), blocking); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 20)
+	if err := l.Enqueue(context.Background(), blocking); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Enqueue(context.Background(), blocking); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected %v; actual %v", ErrQueueFull, err)
+	}
+
+	close(release)
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Enqueue(context.Background(), blocking); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected %v; actual %v", ErrClosed, err)
+	}
+}
+
+func TestLevelerDropOldest(t *testing.T) {
+	release := make(chan struct{})
+	var ran atomic.Int32
+
+	l := New(2, 1, 0, DropOldest)
+	if err := l.Enqueue(context.Background(), func(ctx context.Context) { <-release }); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 20)
+
+	for range 5 {
+		if err := l.Enqueue(context.Background(), func(ctx context.Context) { ran.Add(1) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	close(release)
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := ran.Load(); actual != 2 {
+		t.Errorf("expected only the 2 newest tasks to run; actual %d", actual)
+	}
+
+	if actual := l.Dropped(); actual != 3 {
+		t.Errorf("expected 3 dropped tasks; actual %d", actual)
+	}
+}
+
+func TestLevelerRateAndDrain(t *testing.T) {
+	var ran atomic.Int32
+	l := New(10, 2, time.Millisecond*20, Block)
+
+	start := time.Now()
+	for range 5 {
+		if err := l.Enqueue(context.Background(), func(ctx context.Context) { ran.Add(1) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := ran.Load(); actual != 5 {
+		t.Fatalf("expected shutdown to drain all 5 tasks; actual %d", actual)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Millisecond*100 {
+		t.Errorf("expected tasks to be leveled to one per 20ms; took %s", elapsed)
+	}
+}
+
+func TestLevelerShutdownStuckTask(t *testing.T) {
+	l := New(0, 1, 0, Block)
+
+	//a task that ignores its ctx.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	_ = l.Enqueue(context.Background(), func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	//blocked on the full queue, holding the read lock.
+	enqueued := make(chan error, 1)
+	go func() {
+		enqueued <- l.Enqueue(context.Background(), func(ctx context.Context) {})
+	}()
+	time.Sleep(time.Millisecond * 20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- l.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-shutdown:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v; actual %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Shutdown to return once ctx expired")
+	}
+
+	select {
+	case err := <-enqueued:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("expected %v; actual %v", ErrClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the blocked Enqueue to be woken up")
+	}
+}
+
+func TestLevelerDropOldestNoQueue(t *testing.T) {
+	l := New(0, 0, 0, DropOldest)
+
+	//no worker takes it, the task has to fit in the queue.
+	errs := make(chan error, 1)
+	go func() {
+		errs <- l.Enqueue(context.Background(), func(ctx context.Context) {})
+	}()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Enqueue to return")
+	}
+}
@@ -0,0 +1,177 @@
+package policy
+
+import (
+	"context"
+	"time"
+
+	"networking/stablity-patterns/bulkhead"
+	circuitbreaker "networking/stablity-patterns/circuit-breaker"
+	"networking/stablity-patterns/decorator"
+	"networking/stablity-patterns/fallback"
+	"networking/stablity-patterns/retry"
+	"networking/stablity-patterns/throttle"
+	"networking/stablity-patterns/timeout"
+)
+
+// Effector The function that interacts with the service
+type Effector func(ctx context.Context) (string, error)
+
+// Stage names reported to the observer.
+const (
+	StageFallback = "fallback"
+	StageRetry    = "retry"
+	StageBreaker  = "breaker"
+	StageTimeout  = "timeout"
+	StageBulkhead = "bulkhead"
+	StageThrottle = "throttle"
+)
+
+// Observer is called every time a stage of the policy returns, with how long the stage took
+// including everything nested inside it.
+type Observer func(stage string, d time.Duration, err error)
+
+// fnKey carries the function passed to Execute down to the innermost stage.
+type fnKey struct{}
+
+// Builder collects the patterns of a Policy, every one of them is optional.
+type Builder struct {
+	timeout time.Duration
+
+	retries    int
+	retryDelay time.Duration
+	retry      bool
+
+	failureThreshold int
+
+	maxConcurrent int
+	maxQueue      int
+
+	secondary    Effector
+	fallbackOpts []fallback.Option
+
+	throttleMax    int
+	throttleRefill int
+	throttleEvery  time.Duration
+
+	observer Observer
+}
+
+// NewBuilder creates an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Timeout bounds every attempt to d.
+func (b *Builder) Timeout(d time.Duration) *Builder {
+	b.timeout = d
+	return b
+}
+
+// Retry retries failed attempts up to retries times, delay apart.
+func (b *Builder) Retry(retries int, delay time.Duration) *Builder {
+	b.retry = true
+	b.retries = retries
+	b.retryDelay = delay
+	return b
+}
+
+// Breaker opens the circuit after failureThreshold consecutive failures.
+func (b *Builder) Breaker(failureThreshold int) *Builder {
+	b.failureThreshold = failureThreshold
+	return b
+}
+
+// Bulkhead caps concurrent attempts to maxConcurrent with up to maxQueue waiting.
+func (b *Builder) Bulkhead(maxConcurrent, maxQueue int) *Builder {
+	b.maxConcurrent = maxConcurrent
+	b.maxQueue = maxQueue
+	return b
+}
+
+// Fallback calls secondary when everything else failed.
+func (b *Builder) Fallback(secondary Effector, opts ...fallback.Option) *Builder {
+	b.secondary = secondary
+	b.fallbackOpts = opts
+	return b
+}
+
+// Throttle allows max calls, refilled by refill every d.
+func (b *Builder) Throttle(max, refill int, d time.Duration) *Builder {
+	b.throttleMax = max
+	b.throttleRefill = refill
+	b.throttleEvery = d
+	return b
+}
+
+// Observe registers the telemetry hook shared by every stage.
+func (b *Builder) Observe(observer Observer) *Builder {
+	b.observer = observer
+	return b
+}
+
+// Policy composes the stability patterns in one fixed order, so nobody has to hand-nest
+// Timeout(Retry(Breaker(...))) and get the order subtly wrong. From the outside in:
+//   - Fallback is last resort, it sees the final outcome after every retry.
+//   - Throttle spends one token per Execute, retries don't drain the bucket.
+//   - Retry wraps the breaker, so while the breaker is open retries fail fast.
+//   - Breaker counts every attempt, including the ones that timed out.
+//   - Timeout bounds each attempt, the whole Execute is bounded by the caller's ctx.
+//   - Bulkhead is innermost, so only attempts that are actually running hold a slot.
+//
+// The chain is built once by Build, so the breaker, bulkhead and throttle state is shared by
+// every call to Execute regardless of which fn it runs.
+type Policy struct {
+	effector Effector
+}
+
+// Build assembles the Policy.
+func (b *Builder) Build() *Policy {
+	//innermost stage, runs whatever fn was given to Execute.
+	var e Effector = func(ctx context.Context) (string, error) {
+		return ctx.Value(fnKey{}).(Effector)(ctx)
+	}
+
+	if b.maxConcurrent > 0 {
+		e = b.observe(StageBulkhead, Effector(bulkhead.Bulkhead(bulkhead.Effector(e), b.maxConcurrent, b.maxQueue)))
+	}
+
+	if b.timeout > 0 {
+		e = b.observe(StageTimeout, Effector(timeout.Decorator(b.timeout)(decorator.Effector[string](e))))
+	}
+
+	if b.failureThreshold > 0 {
+		e = b.observe(StageBreaker, Effector(circuitbreaker.Breaker(circuitbreaker.Circuit(e), b.failureThreshold)))
+	}
+
+	if b.retry {
+		e = b.observe(StageRetry, Effector(retry.Retry(retry.Effector(e), b.retries, b.retryDelay)))
+	}
+
+	if b.throttleMax > 0 {
+		e = b.observe(StageThrottle, Effector(throttle.Throttle(throttle.Effector(e), b.throttleMax, b.throttleRefill, b.throttleEvery)))
+	}
+
+	if b.secondary != nil {
+		e = b.observe(StageFallback, Effector(fallback.Fallback(fallback.Effector(e), fallback.Effector(b.secondary), b.fallbackOpts...)))
+	}
+
+	return &Policy{effector: e}
+}
+
+// Execute runs fn through every stage of the policy.
+func (p *Policy) Execute(ctx context.Context, fn Effector) (string, error) {
+	return p.effector(context.WithValue(ctx, fnKey{}, fn))
+}
+
+func (b *Builder) observe(stage string, next Effector) Effector {
+	if b.observer == nil {
+		return next
+	}
+
+	return func(ctx context.Context) (string, error) {
+		start := time.Now()
+		response, err := next(ctx)
+		b.observer(stage, time.Since(start), err)
+		return response, err
+	}
+}
@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	circuitbreaker "networking/stablity-patterns/circuit-breaker"
+)
+
+func TestPolicyRetryThenFallback(t *testing.T) {
+	var mu sync.Mutex
+	stages := make(map[string]int)
+
+	p := NewBuilder().
+		Timeout(time.Millisecond*20).
+		Retry(2, time.Millisecond).
+		Breaker(10).
+		Bulkhead(1, 0).
+		Fallback(func(ctx context.Context) (string, error) {
+			return "fallback", nil
+		}).
+		Observe(func(stage string, d time.Duration, err error) {
+			mu.Lock()
+			stages[stage]++
+			mu.Unlock()
+		}).
+		Build()
+
+	attempts := 0
+	response, err := p.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		attempts++
+		return "", errors.New("down")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response != "fallback" {
+		t.Errorf("expected %q; actual %q", "fallback", response)
+	}
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts; actual %d", attempts)
+	}
+
+	expected := map[string]int{
+		StageFallback: 1,
+		StageRetry:    1,
+		StageBreaker:  3,
+		StageTimeout:  3,
+		StageBulkhead: 3,
+	}
+	for stage, n := range expected {
+		if stages[stage] != n {
+			t.Errorf("expected stage %s to be observed %d times; actual %d", stage, n, stages[stage])
+		}
+	}
+}
+
+func TestPolicySharesState(t *testing.T) {
+	p := NewBuilder().Breaker(2).Build()
+
+	failing := func(ctx context.Context) (string, error) {
+		return "", errors.New("down")
+	}
+
+	for range 2 {
+		_, _ = p.Execute(context.Background(), failing)
+	}
+
+	//a different fn still goes through the same, now open, breaker.
+	_, err := p.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+
+	if !errors.Is(err, circuitbreaker.ErrServiceUnreachable) {
+		t.Fatalf("expected %v; actual %v", circuitbreaker.ErrServiceUnreachable, err)
+	}
+}
+
+func TestPolicyThrottleOutlivesFirstCall(t *testing.T) {
+	p := NewBuilder().Throttle(1, 1, time.Millisecond*20).Build()
+
+	ok := func(ctx context.Context) (string, error) {
+		return "ok", nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := p.Execute(ctx, ok); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	if _, err := p.Execute(context.Background(), ok); err == nil {
+		t.Fatal("expected the bucket to be empty")
+	}
+
+	//the first ctx is gone, but the bucket still refills.
+	time.Sleep(time.Millisecond * 50)
+
+	if _, err := p.Execute(context.Background(), ok); err != nil {
+		t.Fatal(err)
+	}
+}
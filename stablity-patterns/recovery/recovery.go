@@ -0,0 +1,63 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"networking/stablity-patterns/decorator"
+)
+
+// ErrPanic matches, with errors.Is, every error produced from a recovered panic.
+var ErrPanic = errors.New("panic")
+
+// Effector The function that interacts with the service
+type Effector func(ctx context.Context) (string, error)
+
+// PanicError is returned in place of a panic, it holds the recovered value and the stack of
+// the goroutine at the time of the panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Is makes errors.Is(err, ErrPanic) true.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrPanic
+}
+
+// Unwrap returns the recovered value when it's an error, e.g. panic(err).
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Recover converts a panic inside effector into a *PanicError, so a misbehaving dependency
+// call running in a goroutine can't take the whole process down. Put it innermost, so the
+// other patterns see the panic as a regular failure.
+func Recover(effector Effector) Effector {
+	return func(ctx context.Context) (response string, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				response = ""
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+
+		return effector(ctx)
+	}
+}
+
+// Decorator is Recover as a decorator.Decorator.
+func Decorator() decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](Recover(Effector(effector)))
+	}
+}
@@ -0,0 +1,58 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{name: "string", value: "boom"},
+		{name: "error", value: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			effector := Recover(func(ctx context.Context) (string, error) {
+				panic(tt.value)
+			})
+
+			_, err := effector(context.Background())
+			if !errors.Is(err, ErrPanic) {
+				t.Fatalf("expected %v; actual %v", ErrPanic, err)
+			}
+
+			var pErr *PanicError
+			if !errors.As(err, &pErr) {
+				t.Fatalf("expected a *PanicError; actual %T", err)
+			}
+			if !strings.Contains(string(pErr.Stack), "TestRecover") {
+				t.Errorf("expected the stack to contain the panicking call; actual %s", pErr.Stack)
+			}
+
+			if e, ok := tt.value.(error); ok && !errors.Is(err, e) {
+				t.Errorf("expected the panic value %v to be unwrapped", e)
+			}
+		})
+	}
+}
+
+func TestRecoverPassesThrough(t *testing.T) {
+	effector := Recover(func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+
+	res, err := effector(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "ok" {
+		t.Errorf("expected ok; actual %s", res)
+	}
+}
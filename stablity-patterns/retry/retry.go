@@ -2,26 +2,91 @@ package retry
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"networking/stablity-patterns/clock"
+	"networking/stablity-patterns/decorator"
+	"networking/stablity-patterns/telemetry"
 )
 
 // Effector The function that interacts with the service
 type Effector func(ctx context.Context) (string, error)
 
-func Retry(effector Effector, retries int, delay time.Duration) Effector {
+type options struct {
+	clock  clock.Clock
+	tracer trace.Tracer
+	logger telemetry.Logger
+}
+
+// Option configures Retry.
+type Option func(*options)
+
+// WithClock replaces the real clock, mostly so tests can use a clock.Fake.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithTracer enables OpenTelemetry spans, without it no span is ever started.
+func WithTracer(t trace.Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// WithLogger sets the Logger that receives the retry events, the default discards them.
+func WithLogger(l telemetry.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.Real(), logger: telemetry.Nop()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func Retry(effector Effector, retries int, delay time.Duration, opts ...Option) Effector {
+	o := newOptions(opts)
 	return func(ctx context.Context) (string, error) {
+		ctx, span := telemetry.Start(ctx, o.tracer, "retry")
+
 		for r := 0; ; r++ {
-			response, err := effector(ctx)
+			//every attempt gets its own child span.
+			attemptCtx, attempt := telemetry.Start(ctx, o.tracer, "retry.attempt", attribute.Int("retry.attempt", r+1))
+			response, err := effector(attemptCtx)
+			telemetry.End(attempt, err)
+
 			if err == nil || r >= retries {
+				if span.IsRecording() {
+					span.SetAttributes(attribute.Int("retry.attempts", r+1))
+				}
+				telemetry.End(span, err)
 				return response, err
 			}
-			log.Printf("attemp %d failed, retrying in %v\n", r+1, delay)
+			o.logger.Log(ctx, telemetry.EventRetry, slog.String("pattern", "retry"), slog.Int("attempt", r+1),
+				slog.Duration("delay", delay), slog.Any("error", err))
 			select {
-			case <-time.After(delay):
+			case <-o.clock.After(delay):
 			case <-ctx.Done():
+				telemetry.End(span, ctx.Err())
 				return "", ctx.Err()
 			}
 		}
 	}
 }
+
+// Decorator is Retry as a decorator.Decorator.
+func Decorator(retries int, delay time.Duration, opts ...Option) decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](Retry(Effector(effector), retries, delay, opts...))
+	}
+}
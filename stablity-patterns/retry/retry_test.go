@@ -0,0 +1,43 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"networking/stablity-patterns/clock"
+)
+
+func TestRetry(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+
+	attempts := 0
+	withRetry := Retry(func(ctx context.Context) (string, error) {
+		attempts++
+		if attempts < 3 {
+			return "", errors.New("down")
+		}
+		return "ok", nil
+	}, 5, time.Hour, WithClock(fake))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := withRetry(context.Background())
+		done <- err
+	}()
+
+	//an hour of delay each, without sleeping.
+	for range 2 {
+		fake.BlockUntil(1)
+		fake.Advance(time.Hour)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts; actual %d", attempts)
+	}
+}
@@ -0,0 +1,128 @@
+package singleflight
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"networking/stablity-patterns/recovery"
+)
+
+// Effector The function that interacts with the service
+type Effector func(ctx context.Context) (string, error)
+
+// call is an in-flight or finished execution shared by every caller of the same key.
+type call struct {
+	done     chan struct{}
+	response string
+	err      error
+}
+
+type entry struct {
+	response  string
+	expiresAt time.Time
+}
+
+// Group deduplicates concurrent calls by key, so identical calls (e.g. the same cache-miss lookup)
+// share one in-flight execution and all receive its result. With a ttl successful results are
+// also kept around and served for that long without executing again.
+type Group struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*call
+	cache map[string]entry
+	//last time the expired results were swept out of cache.
+	sweptAt time.Time
+}
+
+// NewGroup creates a Group, a zero ttl disables result caching.
+func NewGroup(ttl time.Duration) *Group {
+	return &Group{
+		ttl:   ttl,
+		calls: make(map[string]*call),
+		cache: make(map[string]entry),
+	}
+}
+
+// Do executes effector for key unless an execution for key is already in flight, in that case it
+// waits for it and returns its result. The execution runs with the ctx of the caller that started
+// it, every other caller only stops waiting when its own ctx is done.
+func (g *Group) Do(ctx context.Context, key string, effector Effector) (string, error) {
+	g.mu.Lock()
+
+	if e, ok := g.cache[key]; ok {
+		if time.Now().Before(e.expiresAt) {
+			g.mu.Unlock()
+			return e.response, nil
+		}
+		delete(g.cache, key)
+	}
+
+	c, ok := g.calls[key]
+	if !ok {
+		//we are the first one, so we do the actual call.
+		c = &call{done: make(chan struct{})}
+		g.calls[key] = c
+		g.mu.Unlock()
+
+		g.do(ctx, key, c, effector)
+		return c.response, c.err
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.response, c.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Forget drops the cached result of key, the next call executes again.
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.cache, key)
+}
+
+// sweep drops the expired results of keys nobody asked for again, at most once per ttl so storing
+// stays cheap on average. It must be called with mu held.
+func (g *Group) sweep(now time.Time) {
+	if now.Sub(g.sweptAt) < g.ttl {
+		return
+	}
+	g.sweptAt = now
+
+	for key, e := range g.cache {
+		if !now.Before(e.expiresAt) {
+			delete(g.cache, key)
+		}
+	}
+}
+
+func (g *Group) do(ctx context.Context, key string, c *call, effector Effector) {
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		if c.err == nil && g.ttl > 0 {
+			now := time.Now()
+			g.sweep(now)
+			g.cache[key] = entry{response: c.response, expiresAt: now.Add(g.ttl)}
+		}
+		g.mu.Unlock()
+
+		//wake up everyone waiting on this call.
+		close(c.done)
+	}()
+
+	//a panic fails the call for every waiter instead of handing them an empty success.
+	defer func() {
+		if r := recover(); r != nil {
+			c.response, c.err = "", &recovery.PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	c.response, c.err = effector(ctx)
+}
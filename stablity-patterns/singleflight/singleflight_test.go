@@ -0,0 +1,165 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"networking/stablity-patterns/recovery"
+)
+
+func TestGroupDeduplicates(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	effector := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	g := NewGroup(0)
+
+	var wg sync.WaitGroup
+	results := make(chan string, 5)
+
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := g.Do(context.Background(), "key", effector)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results <- res
+		}()
+	}
+
+	//let every caller join the in-flight call.
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for res := range results {
+		if res != "value" {
+			t.Errorf("expected %q; actual %q", "value", res)
+		}
+	}
+
+	if actual := calls.Load(); actual != 1 {
+		t.Fatalf("expected 1 execution; actual %d", actual)
+	}
+
+	//nothing cached, so a new call executes again.
+	if _, err := g.Do(context.Background(), "key", effector); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := calls.Load(); actual != 2 {
+		t.Fatalf("expected 2 executions; actual %d", actual)
+	}
+}
+
+func TestGroupTTL(t *testing.T) {
+	var calls atomic.Int32
+	effector := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "value", nil
+	}
+
+	g := NewGroup(time.Millisecond * 100)
+
+	for range 3 {
+		if _, err := g.Do(context.Background(), "key", effector); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if actual := calls.Load(); actual != 1 {
+		t.Fatalf("expected cached result to be served; actual %d executions", actual)
+	}
+
+	time.Sleep(time.Millisecond * 150)
+
+	if _, err := g.Do(context.Background(), "key", effector); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := calls.Load(); actual != 2 {
+		t.Fatalf("expected expired result to execute again; actual %d executions", actual)
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	g := NewGroup(time.Minute)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicking := func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		panic("boom")
+	}
+
+	//a waiter sharing the call must see the failure too.
+	waited := make(chan error, 1)
+	go func() {
+		<-started
+		_, err := g.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+			return "unexpected", nil
+		})
+		waited <- err
+	}()
+
+	go func() {
+		<-started
+		//give the waiter time to join the call.
+		time.Sleep(time.Millisecond * 50)
+		close(release)
+	}()
+
+	if _, err := g.Do(context.Background(), "key", panicking); !errors.Is(err, recovery.ErrPanic) {
+		t.Fatalf("expected %v; actual %v", recovery.ErrPanic, err)
+	}
+	if err := <-waited; !errors.Is(err, recovery.ErrPanic) {
+		t.Errorf("expected the waiter to get %v; actual %v", recovery.ErrPanic, err)
+	}
+
+	//nothing was cached, the next call executes.
+	resp, err := g.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+		return "value", nil
+	})
+	if err != nil || resp != "value" {
+		t.Errorf("expected the next call to execute; actual %q, %v", resp, err)
+	}
+}
+
+func TestGroupSweep(t *testing.T) {
+	effector := func(ctx context.Context) (string, error) {
+		return "value", nil
+	}
+
+	g := NewGroup(time.Millisecond * 50)
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := g.Do(context.Background(), key, effector); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//storing a new key drops the expired ones, even though they're never asked for again.
+	time.Sleep(time.Millisecond * 100)
+	if _, err := g.Do(context.Background(), "d", effector); err != nil {
+		t.Fatal(err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.cache) != 1 {
+		t.Errorf("expected only the new result to be cached; actual %d entries", len(g.cache))
+	}
+}
@@ -0,0 +1,56 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Event names shared by every stability pattern, the attrs say which pattern emitted them.
+const (
+	// EventOpen is logged when a breaker opens.
+	EventOpen = "open"
+	// EventClose is logged when an open breaker recovers.
+	EventClose = "close"
+	// EventRetry is logged when a failed attempt is going to be retried.
+	EventRetry = "retry"
+	// EventReject is logged when a call is turned away without being executed.
+	EventReject = "reject"
+	// EventFallback is logged when the fallback path is taken.
+	EventFallback = "fallback"
+	// EventRestart is logged when a supervised function is restarted.
+	EventRestart = "restart"
+	// EventDrop is logged when queued work is discarded.
+	EventDrop = "drop"
+)
+
+// Logger receives the events of the stability patterns. Every pattern defaults to Nop.
+type Logger interface {
+	Log(ctx context.Context, event string, attrs ...slog.Attr)
+}
+
+// Nop returns a Logger that discards everything.
+func Nop() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(ctx context.Context, event string, attrs ...slog.Attr) {}
+
+// Slog adapts l to Logger. Events signalling trouble (open, reject, fallback, restart, drop)
+// are logged at warn level, the rest at info.
+func Slog(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Log(ctx context.Context, event string, attrs ...slog.Attr) {
+	level := slog.LevelWarn
+	if event == EventRetry || event == EventClose {
+		level = slog.LevelInfo
+	}
+	s.l.LogAttrs(ctx, level, event, attrs...)
+}
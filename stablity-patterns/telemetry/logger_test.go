@@ -0,0 +1,32 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := Slog(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	l.Log(context.Background(), EventRetry, slog.String("pattern", "retry"), slog.Int("attempt", 1))
+	l.Log(context.Background(), EventOpen, slog.String("pattern", "breaker"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines; actual %q", buf.String())
+	}
+
+	expected := []string{
+		"level=INFO msg=retry pattern=retry attempt=1",
+		"level=WARN msg=open pattern=breaker",
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("%d: expected %q; actual %q", i, e, lines[i])
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func exampleEffector(ctx context.Context) (string, error) {
+	return "success", nil
+}
+
+func ExampleThrottle() {
+	withThrottle := Throttle(exampleEffector, 3, 1, time.Second)
+	for range 5 {
+		resp, err := withThrottle(context.Background())
+		if err != nil {
+			fmt.Println("Err:", err)
+		} else {
+			fmt.Println("Result:", resp)
+		}
+
+		time.Sleep(time.Millisecond * 300)
+	}
+
+	// Output:
+	// Result: success
+	// Result: success
+	// Result: success
+	// Err: too many calls
+	// Result: success
+}
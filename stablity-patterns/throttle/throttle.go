@@ -1,73 +1,113 @@
-package main
+package throttle
 
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log/slog"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"networking/stablity-patterns/clock"
+	"networking/stablity-patterns/decorator"
+	"networking/stablity-patterns/telemetry"
 )
 
 type Effector func(ctx context.Context) (string, error)
 
-func Throttle(effector Effector, max int, refill int, d time.Duration) Effector {
+type options struct {
+	clock  clock.Clock
+	tracer trace.Tracer
+	logger telemetry.Logger
+}
+
+// Option configures Throttle.
+type Option func(*options)
+
+// WithClock replaces the real clock, mostly so tests can use a clock.Fake.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithTracer enables OpenTelemetry spans, without it no span is ever started.
+func WithTracer(t trace.Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// WithLogger sets the Logger that receives the reject events, the default discards them.
+func WithLogger(l telemetry.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.Real(), logger: telemetry.Nop()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Throttle lets at most max calls through at once and gives refill calls back every d. Tokens are
+// refilled from the time elapsed on each call, so no goroutine outlives the returned Effector and
+// the ctx of a single call has no effect on the others.
+func Throttle(effector Effector, max int, refill int, d time.Duration, opts ...Option) Effector {
+	o := newOptions(opts)
 	// Tracks the number of available "slots" for calls. Initially set to the max value.
 	// Each call to the throttled function decreases the token count.
 	tokens := max
-	// Ensures the refill logic (explained below) is initialized only once, even if the Throttle function is called multiple times.
-	var once sync.Once
+	//the last time tokens were refilled, only advanced by whole periods of d.
+	refilledAt := o.clock.Now()
+	// tokens is shared by every caller, so we need to protect it.
+	var m sync.Mutex
 
 	return func(ctx context.Context) (string, error) {
-		//refill logic
-		once.Do(func() {
-			ticker := time.NewTicker(d) //create a ticker one time only
-			//so now every "d" the ticker will refill to tokens by a fixed amount
-
-			//create a goroutine one time
-			go func() {
-				defer ticker.Stop()
-
-				for {
-					select {
-					case <-ctx.Done():
-						return //so the timer also gets cleaned.
-					case <-ticker.C:
-						//Add refill tokens to the current tokens.
-						t := tokens + refill
-						if t > max {
-							//If tokens exceeds max, reset it to max to ensure we don’t exceed the maximum allowed calls.
-							t = max
-						}
-						tokens = t
-					}
-				}
-			}()
-		})
+		spanCtx, span := telemetry.Start(ctx, o.tracer, "throttle")
+
+		m.Lock()
+		//refill logic: add refill tokens for every d that passed since the last refill.
+		if periods := o.clock.Now().Sub(refilledAt) / d; periods > 0 {
+			//If tokens exceeds max, reset it to max to ensure we don’t exceed the maximum allowed calls.
+			tokens = min(max, tokens+int(periods)*refill)
+			refilledAt = refilledAt.Add(periods * d)
+		}
 
 		if tokens <= 0 {
-			return "", errors.New("too many calls")
+			m.Unlock()
+			err := errors.New("too many calls")
+			if span.IsRecording() {
+				span.SetAttributes(attribute.Int("throttle.tokens_remaining", 0), attribute.Bool("throttle.rejected", true))
+			}
+			telemetry.End(span, err)
+			o.logger.Log(spanCtx, telemetry.EventReject, slog.String("pattern", "throttle"))
+			return "", err
 		}
 
 		tokens--
+		remaining := tokens
+		m.Unlock()
+
+		if span.IsRecording() {
+			span.SetAttributes(attribute.Int("throttle.tokens_remaining", remaining), attribute.Bool("throttle.rejected", false))
+		}
+
 		//do the call
-		return effector(ctx)
+		response, err := effector(spanCtx)
+		telemetry.End(span, err)
+		return response, err
 	}
 }
 
-func exampleEffector(ctx context.Context) (string, error) {
-	return "success", nil
-}
-
-func main() {
-	withThrottle := Throttle(exampleEffector, 3, 1, time.Second)
-	for range 5 {
-		resp, err := withThrottle(context.Background())
-		if err != nil {
-			fmt.Println("Err:", err)
-		} else {
-			fmt.Println("Result:", resp)
-		}
-
-		time.Sleep(time.Millisecond * 300)
+// Decorator is Throttle as a decorator.Decorator, every effector it decorates gets its own bucket.
+func Decorator(max int, refill int, d time.Duration, opts ...Option) decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		return decorator.Effector[string](Throttle(Effector(effector), max, refill, d, opts...))
 	}
 }
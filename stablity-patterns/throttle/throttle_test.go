@@ -0,0 +1,51 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"networking/stablity-patterns/clock"
+)
+
+func TestThrottleRefill(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	withThrottle := Throttle(exampleEffector, 2, 1, time.Minute, WithClock(fake))
+
+	ctx := context.Background()
+	for range 2 {
+		if _, err := withThrottle(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := withThrottle(ctx); err == nil {
+		t.Fatal("expected the bucket to be empty")
+	}
+
+	fake.Advance(time.Minute)
+	if _, err := withThrottle(ctx); err != nil {
+		t.Fatalf("expected a token after the refill; actual %v", err)
+	}
+}
+
+func TestThrottleCancelledCaller(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	withThrottle := Throttle(exampleEffector, 1, 1, time.Minute, WithClock(fake))
+
+	//the first caller going away must not stop the refill for the others.
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := withThrottle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	fake.Advance(time.Minute * 3)
+	if _, err := withThrottle(context.Background()); err != nil {
+		t.Fatalf("expected a token after the refill; actual %v", err)
+	}
+	//refills never go above max.
+	if _, err := withThrottle(context.Background()); err == nil {
+		t.Fatal("expected the bucket to be capped at max")
+	}
+}
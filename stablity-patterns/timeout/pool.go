@@ -0,0 +1,104 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrTooManyAbandoned is returned when the number of abandoned calls still running
+// has reached the pool's limit, so no new call is started.
+var ErrTooManyAbandoned = errors.New("too many abandoned calls")
+
+// call states, a call is either still running, finished, or abandoned by its caller.
+const (
+	running int32 = iota
+	finished
+	abandoned
+)
+
+// Pool runs slow calls on a bounded number of goroutines. A call whose ctx is done before it
+// returns is abandoned: its goroutine keeps running until the slow function returns, so the
+// pool counts those goroutines and refuses new calls once too many of them pile up.
+type Pool struct {
+	//worker slots, each running call holds one until the slow function returns.
+	workers chan struct{}
+	//maximum number of abandoned calls allowed to be running, 0 means unlimited.
+	maxAbandoned int64
+	//abandoned calls that are still running.
+	abandoned atomic.Int64
+	//every call ever abandoned.
+	totalAbandoned atomic.Int64
+}
+
+// NewPool creates a Pool with at most workers concurrent calls and at most maxAbandoned of
+// them abandoned at the same time. workers is at least 1, without a slot every call would
+// wait for its ctx.
+func NewPool(workers int, maxAbandoned int) *Pool {
+	return &Pool{
+		workers:      make(chan struct{}, max(workers, 1)),
+		maxAbandoned: int64(maxAbandoned),
+	}
+}
+
+// Abandoned returns the number of abandoned calls whose goroutines are still running.
+func (p *Pool) Abandoned() int64 {
+	return p.abandoned.Load()
+}
+
+// TotalAbandoned returns the number of calls abandoned since the pool was created.
+func (p *Pool) TotalAbandoned() int64 {
+	return p.totalAbandoned.Load()
+}
+
+// Timeout is like the package level Timeout but runs the slow calls on the pool.
+func (p *Pool) Timeout(slow SlowFunc) WithContext {
+	return func(ctx context.Context, data string) (string, error) {
+		if p.maxAbandoned > 0 && p.abandoned.Load() >= p.maxAbandoned {
+			return "", ErrTooManyAbandoned
+		}
+
+		//wait for a free worker, or give up with the caller.
+		select {
+		case p.workers <- struct{}{}:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+
+		type result struct {
+			res string
+			err error
+		}
+
+		resCh := make(chan result, 1)
+		var state atomic.Int32
+
+		go func() {
+			defer func() { <-p.workers }()
+
+			res, err := slow(data)
+			if err != nil {
+				err = fmt.Errorf("slow: %w", err)
+			}
+			resCh <- result{res, err}
+
+			//the caller already gave up on us, so we were counted as abandoned.
+			if !state.CompareAndSwap(running, finished) {
+				p.abandoned.Add(-1)
+			}
+		}()
+
+		select {
+		case r := <-resCh:
+			return r.res, r.err
+		case <-ctx.Done():
+			//the call might have finished right at the same time, only count it when it did not.
+			if state.CompareAndSwap(running, abandoned) {
+				p.abandoned.Add(1)
+				p.totalAbandoned.Add(1)
+			}
+			return "", ctx.Err()
+		}
+	}
+}
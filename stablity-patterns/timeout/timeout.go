@@ -1,4 +1,4 @@
-package main
+package timeout
 
 import (
 	"context"
@@ -10,6 +10,8 @@ type SlowFunc func(string) (string, error)
 
 type WithContext func(ctx context.Context, data string) (string, error)
 
+// Timeout gives slow a ctx to be controlled with. When ctx is done first the goroutine running
+// slow is abandoned and keeps running until slow returns, use a Pool to bound and observe those.
 func Timeout(slow SlowFunc) WithContext {
 	return func(ctx context.Context, data string) (string, error) {
 		resCh := make(chan string, 1)
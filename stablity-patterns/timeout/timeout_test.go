@@ -0,0 +1,110 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoolAbandoned(t *testing.T) {
+	release := make(chan struct{})
+	slow := func(data string) (string, error) {
+		<-release
+		return data, nil
+	}
+
+	pool := NewPool(10, 2)
+	withCtx := pool.Timeout(slow)
+
+	for range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		_, err := withCtx(ctx, "hello")
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected %v; actual %v", context.DeadlineExceeded, err)
+		}
+	}
+
+	if actual := pool.Abandoned(); actual != 2 {
+		t.Fatalf("expected 2 abandoned calls; actual %d", actual)
+	}
+
+	//limit reached, no new goroutine should be started.
+	if _, err := withCtx(context.Background(), "hello"); !errors.Is(err, ErrTooManyAbandoned) {
+		t.Fatalf("expected %v; actual %v", ErrTooManyAbandoned, err)
+	}
+
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for pool.Abandoned() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected abandoned calls to drain; actual %d", pool.Abandoned())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	res, err := withCtx(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res != "hello" {
+		t.Errorf("expected %q; actual %q", "hello", res)
+	}
+
+	if actual := pool.TotalAbandoned(); actual != 2 {
+		t.Errorf("expected 2 total abandoned calls; actual %d", actual)
+	}
+}
+
+func TestPoolNoWorkers(t *testing.T) {
+	slow := func(data string) (string, error) {
+		return data, nil
+	}
+
+	//zero and negative sizes still get a worker instead of blocking or panicking.
+	for _, workers := range []int{0, -1} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		res, err := NewPool(workers, 0).Timeout(slow)(ctx, "hello")
+		cancel()
+		if err != nil {
+			t.Fatalf("workers %d: %v", workers, err)
+		}
+		if res != "hello" {
+			t.Errorf("workers %d: expected %q; actual %q", workers, "hello", res)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	square := Wrap(func(n int) (int, error) {
+		time.Sleep(time.Millisecond * 10)
+		return n * n, nil
+	})
+
+	out, err := square(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out != 16 {
+		t.Errorf("expected %d; actual %d", 16, out)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	block := WrapErr(func(ch chan struct{}) error {
+		<-ch
+		return nil
+	})
+
+	ch := make(chan struct{})
+	defer close(ch)
+
+	if err := block(ctx, ch); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,62 @@
+package timeout
+
+import (
+	"context"
+	"time"
+
+	"networking/stablity-patterns/decorator"
+)
+
+// Wrap is the generic form of Timeout, it gives any blocking fn that doesn't accept a ctx
+// a ctx to be controlled with. The same abandonment caveat as Timeout applies.
+func Wrap[In, Out any](fn func(In) (Out, error)) func(ctx context.Context, in In) (Out, error) {
+	return func(ctx context.Context, in In) (Out, error) {
+		type result struct {
+			out Out
+			err error
+		}
+
+		resCh := make(chan result, 1)
+
+		go func() {
+			out, err := fn(in)
+			resCh <- result{out, err}
+		}()
+
+		select {
+		case r := <-resCh:
+			return r.out, r.err
+		case <-ctx.Done():
+			var zero Out
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// WrapErr is Wrap for functions that only return an error.
+func WrapErr[In any](fn func(In) error) func(ctx context.Context, in In) error {
+	withCtx := Wrap(func(in In) (struct{}, error) {
+		return struct{}{}, fn(in)
+	})
+
+	return func(ctx context.Context, in In) error {
+		_, err := withCtx(ctx, in)
+		return err
+	}
+}
+
+// Decorator bounds every call of the decorated effector to d. Like Wrap it returns once d
+// passes even when the effector ignores its ctx.
+func Decorator(d time.Duration) decorator.Decorator[string] {
+	return func(effector decorator.Effector[string]) decorator.Effector[string] {
+		wrapped := Wrap(func(ctx context.Context) (string, error) {
+			return effector(ctx)
+		})
+
+		return func(ctx context.Context) (string, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return wrapped(ctx, ctx)
+		}
+	}
+}
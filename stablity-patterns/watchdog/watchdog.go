@@ -0,0 +1,140 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"networking/stablity-patterns/telemetry"
+)
+
+// ErrStalled is reported when the supervised function stops sending heartbeats.
+var ErrStalled = errors.New("heartbeat timeout")
+
+// Func is a long-lived function, e.g. an accept loop, that calls beat regularly to prove it's
+// still making progress and stops once ctx is done.
+type Func func(ctx context.Context, beat func()) error
+
+type options struct {
+	backoff    time.Duration
+	maxBackoff time.Duration
+	onRestart  func(err error, attempt int)
+	logger     telemetry.Logger
+}
+
+// Option configures Supervise.
+type Option func(*options)
+
+// WithBackoff sets the delay before the first restart, it doubles on every consecutive
+// restart up to max. Defaults to 100ms and 30s.
+func WithBackoff(base, max time.Duration) Option {
+	return func(o *options) {
+		o.backoff = base
+		o.maxBackoff = max
+	}
+}
+
+// OnRestart registers a callback invoked with the reason every time the function is restarted.
+func OnRestart(fn func(err error, attempt int)) Option {
+	return func(o *options) {
+		o.onRestart = fn
+	}
+}
+
+// WithLogger sets the Logger that receives the restart events, the default discards them.
+func WithLogger(l telemetry.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// Supervise runs fn and restarts it with exponential backoff whenever it returns an error or
+// doesn't beat for longer than timeout. A stalled run gets its ctx cancelled and is abandoned.
+// Supervise returns nil once fn returns nil, or ctx.Err() once ctx is done.
+func Supervise(ctx context.Context, fn Func, timeout time.Duration, opts ...Option) error {
+	o := options{
+		backoff:    time.Millisecond * 100,
+		maxBackoff: time.Second * 30,
+		logger:     telemetry.Nop(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	//consecutive restarts without a single heartbeat in between.
+	attempt := 0
+
+	for {
+		healthy, err := run(ctx, fn, timeout)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		//the run made progress before failing, so start the backoff over.
+		if healthy {
+			attempt = 0
+		}
+
+		o.logger.Log(ctx, telemetry.EventRestart, slog.String("pattern", "watchdog"), slog.Int("attempt", attempt+1), slog.Any("error", err))
+		if o.onRestart != nil {
+			o.onRestart(err, attempt+1)
+		}
+
+		delay := o.backoff << attempt
+		if delay > o.maxBackoff || delay <= 0 {
+			delay = o.maxBackoff
+		}
+		attempt++
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// run runs fn once and reports whether it sent at least one heartbeat.
+func run(ctx context.Context, fn Func, timeout time.Duration) (bool, error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	//each run has its own channel, so a late beat from an abandoned run is ignored.
+	beats := make(chan struct{}, 1)
+	beat := func() {
+		select {
+		case beats <- struct{}{}:
+		default:
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fn(runCtx, beat)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	healthy := false
+	for {
+		select {
+		case <-beats:
+			healthy = true
+			timer.Reset(timeout)
+		case err := <-errCh:
+			if err != nil {
+				return healthy, fmt.Errorf("run: %w", err)
+			}
+			return healthy, nil
+		case <-timer.C:
+			return healthy, ErrStalled
+		}
+	}
+}
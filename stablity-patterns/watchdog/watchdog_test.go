@@ -0,0 +1,68 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSuperviseRestartsOnStall(t *testing.T) {
+	runs := 0
+	var reasons []error
+
+	fn := func(ctx context.Context, beat func()) error {
+		runs++
+		if runs < 3 {
+			//stall without beating.
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		beat()
+		return nil
+	}
+
+	err := Supervise(context.Background(), fn, time.Millisecond*20,
+		WithBackoff(time.Millisecond, time.Millisecond*10),
+		OnRestart(func(err error, attempt int) {
+			reasons = append(reasons, err)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runs != 3 {
+		t.Fatalf("expected 3 runs; actual %d", runs)
+	}
+
+	for _, r := range reasons {
+		if !errors.Is(r, ErrStalled) {
+			t.Errorf("expected restart reason %v; actual %v", ErrStalled, r)
+		}
+	}
+}
+
+func TestSuperviseRestartsOnError(t *testing.T) {
+	errBoom := errors.New("boom")
+	runs := 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+
+	fn := func(ctx context.Context, beat func()) error {
+		runs++
+		return errBoom
+	}
+
+	err := Supervise(ctx, fn, time.Second, WithBackoff(time.Millisecond*10, time.Millisecond*40))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+
+	//10 + 20 + 40 + 40... so only a handful of runs fit into 200ms.
+	if runs < 3 || runs > 8 {
+		t.Errorf("expected backoff to space out the restarts; actual %d runs", runs)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// role is what a peer may do on the admin socket.
+type role int
+
+const (
+	// readOnly peers can run commands that only report state.
+	readOnly role = iota
+	// readWrite peers can also run commands that change it.
+	readWrite
+)
+
+func (r role) String() string {
+	if r == readWrite {
+		return "read-write"
+	}
+	return "read-only"
+}
+
+// peer is the identity of an authorized connection.
+type peer struct {
+	uid  uint32
+	gid  uint32
+	gids []string
+}
+
+// command is a single admin command, mutating ones need readWrite and are audited.
+type command struct {
+	mutating bool
+	run      func(a *admin, args []string) (string, error)
+}
+
+var commands = map[string]command{
+	"stats": {run: func(a *admin, args []string) (string, error) {
+		return fmt.Sprintf("running=%d draining=%t", a.quotas.running(), a.draining.Load()), nil
+	}},
+	"limits": {mutating: true, run: func(a *admin, args []string) (string, error) {
+		//limits uid|gid <rate> <concurrent>
+		if len(args) != 3 || (args[0] != "uid" && args[0] != "gid") {
+			return "", fmt.Errorf("usage: limits uid|gid <rate> <concurrent>")
+		}
+		rate, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return "", fmt.Errorf("rate: %w", err)
+		}
+		concurrent, err := strconv.Atoi(args[2])
+		if err != nil {
+			return "", fmt.Errorf("concurrent: %w", err)
+		}
+		a.quotas.set(args[0] == "gid", limits{rate: rate, concurrent: concurrent})
+		return "ok", nil
+	}},
+	"drain": {mutating: true, run: func(a *admin, args []string) (string, error) {
+		a.draining.Store(true)
+		return "ok", nil
+	}},
+	"shutdown": {mutating: true, run: func(a *admin, args []string) (string, error) {
+		if a.listener != nil {
+			if err := a.listener.Close(); err != nil {
+				return "", fmt.Errorf("close: %w", err)
+			}
+		}
+		return "ok", nil
+	}},
+}
+
+// admin serves the commands of authorized peers. Peers in one of rwGroups get readWrite, every
+// other authorized peer readOnly, and every mutating call, allowed or not, goes to audit.
+type admin struct {
+	quotas   *quotas
+	listener *net.UnixListener
+	audit    *log.Logger
+	rwGroups map[string]struct{}
+	//a connection without a command for that long is closed, zero means never.
+	idle time.Duration
+	//new connections are turned away once set.
+	draining atomic.Bool
+}
+
+func (a *admin) roleOf(p *peer) role {
+	for _, gid := range p.gids {
+		if _, ok := a.rwGroups[gid]; ok {
+			return readWrite
+		}
+	}
+	return readOnly
+}
+
+// handle runs the commands of conn, one per line, until the peer hangs up or stays idle for
+// too long. Every command goes through the quotas of p on its own, a single connection gets no
+// more than separate ones would.
+func (a *admin) handle(conn net.Conn, p *peer) {
+	defer conn.Close()
+
+	r := a.roleOf(p)
+	scanner := bufio.NewScanner(conn)
+	for {
+		if a.idle > 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(a.idle)); err != nil {
+				return
+			}
+		}
+		if !scanner.Scan() {
+			return
+		}
+
+		var res string
+		release, err := a.quotas.acquire(p.uid, p.gid)
+		if err != nil {
+			res = "error: quota exceeded: " + err.Error()
+		} else {
+			res = a.exec(p, r, scanner.Text())
+			release()
+		}
+
+		if _, err := conn.Write([]byte(res + "\n")); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+}
+
+// exec runs a single command line for p and returns the response to send back.
+func (a *admin) exec(p *peer, r role, line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "error: empty command"
+	}
+
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		return fmt.Sprintf("error: unknown command %q", fields[0])
+	}
+
+	if cmd.mutating && r != readWrite {
+		a.audit.Printf("uid=%d gid=%d role=%s command=%q result=denied", p.uid, p.gid, r, line)
+		return fmt.Sprintf("error: %s requires read-write", fields[0])
+	}
+
+	res, err := cmd.run(a, fields[1:])
+	if cmd.mutating {
+		result := "ok"
+		if err != nil {
+			result = "error: " + err.Error()
+		}
+		a.audit.Printf("uid=%d gid=%d role=%s command=%q result=%q", p.uid, p.gid, r, line, result)
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return res
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminExec(t *testing.T) {
+	var audit bytes.Buffer
+	a := &admin{
+		quotas:   newQuotas(limits{}, limits{}),
+		audit:    log.New(&audit, "", 0),
+		rwGroups: map[string]struct{}{"10": {}},
+	}
+
+	operator := &peer{uid: 1000, gid: 100, gids: []string{"100", "10"}}
+	viewer := &peer{uid: 1001, gid: 100, gids: []string{"100"}}
+
+	tests := []struct {
+		name     string
+		peer     *peer
+		line     string
+		expected string
+	}{
+		{name: "read-only stats", peer: viewer, line: "stats", expected: "running=0 draining=false"},
+		{name: "read-only drain", peer: viewer, line: "drain", expected: "error: drain requires read-write"},
+		{name: "read-write limits", peer: operator, line: "limits uid 5 2", expected: "ok"},
+		{name: "read-write bad limits", peer: operator, line: "limits uid x 2", expected: "error: rate:"},
+		{name: "read-write drain", peer: operator, line: "drain", expected: "ok"},
+		{name: "unknown", peer: operator, line: "reboot", expected: `error: unknown command "reboot"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := a.exec(tt.peer, a.roleOf(tt.peer), tt.line)
+			if !strings.HasPrefix(actual, tt.expected) {
+				t.Errorf("expected %q; actual %q", tt.expected, actual)
+			}
+		})
+	}
+
+	if !a.draining.Load() {
+		t.Error("expected the server to be draining")
+	}
+	if a.quotas.uid != (limits{rate: 5, concurrent: 2}) {
+		t.Errorf("expected the new uid limits; actual %+v", a.quotas.uid)
+	}
+
+	//only the mutating calls are audited, denied ones included.
+	expected := []string{
+		`uid=1001 gid=100 role=read-only command="drain" result=denied`,
+		`uid=1000 gid=100 role=read-write command="limits uid 5 2" result="ok"`,
+		`uid=1000 gid=100 role=read-write command="limits uid x 2" result="error: rate:`,
+		`uid=1000 gid=100 role=read-write command="drain" result="ok"`,
+	}
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d audit lines; actual %q", len(expected), audit.String())
+	}
+	for i, e := range expected {
+		if !strings.HasPrefix(lines[i], e) {
+			t.Errorf("%d: expected %q; actual %q", i, e, lines[i])
+		}
+	}
+}
+
+func TestAdminHandle(t *testing.T) {
+	a := &admin{
+		quotas: newQuotas(limits{rate: 2}, limits{}),
+		audit:  log.New(io.Discard, "", 0),
+		idle:   time.Millisecond * 200,
+	}
+	//the clock stands still, the bucket of the uid never refills.
+	now := time.Now()
+	a.quotas.now = func() time.Time { return now }
+
+	server, client := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.handle(server, &peer{uid: 1000, gid: 100, gids: []string{"100"}})
+	}()
+
+	//every command on the same connection is charged to the uid.
+	r := bufio.NewReader(client)
+	expected := []string{"running=1", "running=1", "error: quota exceeded"}
+	for i, e := range expected {
+		if _, err := client.Write([]byte("stats\n")); err != nil {
+			t.Fatal(err)
+		}
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(line, e) {
+			t.Errorf("%d: expected %q; actual %q", i, e, line)
+		}
+	}
+
+	//a silent peer is hung up on and holds no slot in between.
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the idle connection to be closed")
+	}
+	if n := a.quotas.running(); n != 0 {
+		t.Errorf("expected no running command; actual %d", n)
+	}
+}
@@ -3,12 +3,16 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"net"
 	"os"
 	"os/signal"
 	"os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
@@ -21,10 +25,21 @@ func init() {
 
 }
 
+var (
+	uidRate       = flag.Float64("uid-rate", 0, "commands per second allowed per UID, 0 means unlimited")
+	uidConcurrent = flag.Int("uid-concurrent", 0, "concurrent commands allowed per UID, 0 means unlimited")
+	gidRate       = flag.Float64("gid-rate", 0, "commands per second allowed per GID, 0 means unlimited")
+	gidConcurrent = flag.Int("gid-concurrent", 0, "concurrent commands allowed per GID, 0 means unlimited")
+	adminGroups   = flag.String("admin-groups", "", "comma separated groups allowed to run mutating commands, the others are read-only")
+	auditPath     = flag.String("audit", "", "file the mutating commands are logged to, stderr by default")
+	idle          = flag.Duration("idle", time.Minute, "close connections that send no command for that long, 0 means never")
+)
+
 func main() {
 	flag.Parse()
 
 	groups := parseGroupNames(flag.Args())
+	q := newQuotas(limits{rate: *uidRate, concurrent: *uidConcurrent}, limits{rate: *gidRate, concurrent: *gidConcurrent})
 	socket := filepath.Join(os.TempDir(), "creds.sock")
 
 	addr, err := net.ResolveUnixAddr("unix", socket)
@@ -39,6 +54,29 @@ func main() {
 		os.Exit(1)
 	}
 
+	audit := os.Stderr
+	if *auditPath != "" {
+		audit, err = os.OpenFile(*auditPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer audit.Close()
+	}
+
+	var rwGroups []string
+	if *adminGroups != "" {
+		rwGroups = strings.Split(*adminGroups, ",")
+	}
+
+	a := &admin{
+		quotas:   q,
+		listener: s,
+		audit:    log.New(audit, "audit: ", log.LstdFlags),
+		rwGroups: parseGroupNames(rwGroups),
+		idle:     *idle,
+	}
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGINT)
 
@@ -55,16 +93,22 @@ func main() {
 			break
 		}
 
-		if allowed(conn, groups) {
-			_, err := conn.Write([]byte("Welcome\n"))
+		if a.draining.Load() {
+			_, _ = conn.Write([]byte("Draining\n"))
+			_ = conn.Close()
+			continue
+		}
+
+		if p, ok := allowed(conn, groups); ok {
+			_, err = conn.Write([]byte("Welcome\n"))
 			if err != nil {
 				fmt.Println(err)
 				_ = conn.Close()
 				return
 			}
 
-			//handle the conn in goroutine
-			go handler(conn)
+			//handle the conn in goroutine, its commands are checked against the quotas one by one.
+			go a.handle(conn, p)
 
 		} else {
 			_, err := conn.Write([]byte("Access Denied\n"))
@@ -77,11 +121,6 @@ func main() {
 	}
 }
 
-func handler(conn *net.UnixConn) {
-	defer conn.Close()
-	conn.Write([]byte("Passed Authentication"))
-}
-
 func parseGroupNames(args []string) map[string]struct{} {
 	groups := make(map[string]struct{}, len(args))
 
@@ -98,16 +137,17 @@ func parseGroupNames(args []string) map[string]struct{} {
 	return groups
 }
 
-func allowed(conn *net.UnixConn, groups map[string]struct{}) bool {
+// allowed reports whether the peer of conn is in one of groups, and returns its identity when it is.
+func allowed(conn *net.UnixConn, groups map[string]struct{}) (*peer, bool) {
 	if conn == nil || groups == nil || len(groups) == 0 {
-		return false
+		return nil, false
 	}
 
 	//access the file for the other peer.
 	file, err := conn.File()
 	if err != nil {
 		fmt.Println(err)
-		return false
+		return nil, false
 	}
 
 	defer func() { _ = file.Close() }()
@@ -119,31 +159,31 @@ func allowed(conn *net.UnixConn, groups map[string]struct{}) bool {
 		uCred, err = unix.GetsockoptUcred(int(file.Fd()), unix.SOL_SOCKET, unix.SO_PEERCRED)
 		if err != nil {
 			fmt.Println(err)
-			return false
+			return nil, false
 		}
 		break
 	}
 
 	//pass the uid to get a *user.User back and on that we can get its groups
-	u, err := user.LookupId(string(uCred.Uid))
+	u, err := user.LookupId(strconv.FormatUint(uint64(uCred.Uid), 10))
 	if err != nil {
 		fmt.Println(err)
-		return false
+		return nil, false
 	}
 
 	//groups
 	gids, err := u.GroupIds()
 	if err != nil {
 		fmt.Println(err)
-		return false
+		return nil, false
 	}
 
 	//if the user is in valid groups it can proceed
 	for _, gid := range gids {
 		if _, ok := groups[gid]; ok {
-			return true
+			return &peer{uid: uCred.Uid, gid: uCred.Gid, gids: gids}, true
 		}
 	}
 
-	return false
+	return nil, false
 }
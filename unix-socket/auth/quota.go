@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// errRateLimited is returned when an identity sends commands faster than its rate.
+	errRateLimited = errors.New("rate limited")
+	// errTooManyCommands is returned when an identity already has its maximum of commands running.
+	errTooManyCommands = errors.New("too many concurrent commands")
+)
+
+// limits is the quota of a single identity, a zero field disables that limit.
+type limits struct {
+	//commands per second, with a burst of the same size but at least one command.
+	rate float64
+	//commands running at the same time.
+	concurrent int
+}
+
+// burst is the most commands l lets through at once. A rate below one still admits a command
+// every 1/rate seconds, with a burst of the rate itself the bucket would never reach a token.
+func (l limits) burst() float64 {
+	return max(l.rate, 1)
+}
+
+// usage is what a single identity has consumed of its limits.
+type usage struct {
+	tokens   float64
+	refillAt time.Time
+	running  int
+}
+
+// quotas enforces per-UID and per-GID limits on the commands of authorized peers, so a local
+// tool that is allowed in but misbehaves can't wedge the daemon. A command has to fit in the
+// quota of its UID and of its GID.
+type quotas struct {
+	uid limits
+	gid limits
+	now func() time.Time
+
+	mu    sync.Mutex
+	byUID map[uint32]*usage
+	byGID map[uint32]*usage
+}
+
+func newQuotas(uid, gid limits) *quotas {
+	return &quotas{
+		uid:   uid,
+		gid:   gid,
+		now:   time.Now,
+		byUID: make(map[uint32]*usage),
+		byGID: make(map[uint32]*usage),
+	}
+}
+
+// acquire admits a command of uid and gid, release must be called once the command is done.
+func (q *quotas) acquire(uid, gid uint32) (release func(), err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	u := usageOf(q.byUID, uid, q.uid, now)
+	g := usageOf(q.byGID, gid, q.gid, now)
+
+	//check both before taking anything, so a rejected command consumes nothing.
+	if err := check(u, q.uid, now); err != nil {
+		return nil, err
+	}
+	if err := check(g, q.gid, now); err != nil {
+		return nil, err
+	}
+
+	take(u, q.uid)
+	take(g, q.gid)
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			u.running--
+			g.running--
+		})
+	}
+	return release, nil
+}
+
+func usageOf(m map[uint32]*usage, id uint32, l limits, now time.Time) *usage {
+	u, ok := m[id]
+	if !ok {
+		u = &usage{tokens: l.burst(), refillAt: now}
+		m[id] = u
+	}
+	return u
+}
+
+// check refills the tokens of u and reports whether one more command fits in l.
+func check(u *usage, l limits, now time.Time) error {
+	if l.rate > 0 {
+		u.tokens = min(l.burst(), u.tokens+now.Sub(u.refillAt).Seconds()*l.rate)
+		u.refillAt = now
+		if u.tokens < 1 {
+			return errRateLimited
+		}
+	}
+
+	if l.concurrent > 0 && u.running >= l.concurrent {
+		return errTooManyCommands
+	}
+	return nil
+}
+
+func take(u *usage, l limits) {
+	if l.rate > 0 {
+		u.tokens--
+	}
+	u.running++
+}
+
+// set replaces the limits of every UID, or of every GID, taking effect from the next command.
+func (q *quotas) set(gid bool, l limits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if gid {
+		q.gid = l
+		return
+	}
+	q.uid = l
+}
+
+// running returns the number of commands currently admitted.
+func (q *quotas) running() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := 0
+	for _, u := range q.byUID {
+		n += u.running
+	}
+	return n
+}
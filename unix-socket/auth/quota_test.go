@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuotasConcurrent(t *testing.T) {
+	q := newQuotas(limits{concurrent: 2}, limits{concurrent: 3})
+
+	r1, err := q.acquire(1000, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.acquire(1000, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.acquire(1000, 100); !errors.Is(err, errTooManyCommands) {
+		t.Fatalf("expected %v for the uid; actual %v", errTooManyCommands, err)
+	}
+
+	//another user of the same group still fits in the group's quota, once.
+	if _, err := q.acquire(1001, 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.acquire(1002, 100); !errors.Is(err, errTooManyCommands) {
+		t.Fatalf("expected %v for the gid; actual %v", errTooManyCommands, err)
+	}
+
+	r1()
+	//releasing twice must not free a second slot.
+	r1()
+	if _, err := q.acquire(1002, 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.acquire(1003, 100); !errors.Is(err, errTooManyCommands) {
+		t.Fatalf("expected %v; actual %v", errTooManyCommands, err)
+	}
+}
+
+func TestQuotasRate(t *testing.T) {
+	now := time.Now()
+	q := newQuotas(limits{rate: 2}, limits{})
+	q.now = func() time.Time { return now }
+
+	for range 2 {
+		release, err := q.acquire(1000, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	if _, err := q.acquire(1000, 100); !errors.Is(err, errRateLimited) {
+		t.Fatalf("expected %v; actual %v", errRateLimited, err)
+	}
+
+	//other users have their own bucket.
+	if _, err := q.acquire(1001, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	//half a second refills one token at 2/s.
+	now = now.Add(time.Millisecond * 500)
+	if _, err := q.acquire(1000, 100); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuotasFractionalRate(t *testing.T) {
+	now := time.Now()
+	q := newQuotas(limits{rate: 0.5}, limits{})
+	q.now = func() time.Time { return now }
+
+	release, err := q.acquire(1000, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	if _, err := q.acquire(1000, 100); !errors.Is(err, errRateLimited) {
+		t.Fatalf("expected %v; actual %v", errRateLimited, err)
+	}
+
+	//one command every two seconds at 0.5/s.
+	now = now.Add(time.Second * 2)
+	if _, err := q.acquire(1000, 100); err != nil {
+		t.Fatal(err)
+	}
+}